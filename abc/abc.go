@@ -0,0 +1,555 @@
+/*
+Approximate Bayesian computation (rejection ABC); parameters are drawn from priors, each draw is simulated by a separate invade process
+and the summary statistics of the simulation are compared to observed statistics
+*/
+package abc
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"invade/io/writer"
+	"invade/popgen"
+	"invade/runner"
+	"invade/util"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	PRIORUNIFORM    string = "uniform"
+	PRIORLOGUNIFORM string = "loguniform"
+)
+
+/*
+A prior of a command line parameter of the simulations (e.g. u or cluster);
+the value is drawn uniformly or log-uniformly between min and max and inserted into the template, e.g. 'kb:{},{}'
+*/
+type Prior struct {
+	Name         string
+	Distribution string
+	Min          float64
+	Max          float64
+	Integer      bool   // round the drawn values
+	template     string // each '{}' is replaced by the drawn value
+}
+
+/*
+A draw from the priors; simulated with its own seed, derived from the seed of the ABC
+*/
+type Draw struct {
+	Index  int64
+	Values []float64
+	Seed   int64
+}
+
+type ABC struct {
+	priors    []Prior
+	observed  map[string]float64
+	statnames []string // the statistics of the observed file, in the order of the summary statistics
+	args      []string // command line parameters passed to every simulation
+	draws     int64
+	tolerance float64
+	threads   int64
+	seed      int64
+	out       *writer.Output
+}
+
+type drawResult struct {
+	draw     Draw
+	stats    []float64
+	distance float64
+	err      error
+}
+
+var abcFlags = map[string]bool{"priors": false, "observed": false, "draws": false, "tolerance": false, "threads": false, "seed": false, "out": false, "restart": true} // name -> is boolean
+
+var priorValue = regexp.MustCompile(`^(uniform|loguniform)\(\s*([^,\s]+)\s*,\s*([^,\s]+)\s*(,\s*int\s*)?\)$`)
+
+func PrintUsage() {
+	fmt.Fprintln(os.Stderr, `Usage: invade abc --priors "u=loguniform(0.001,0.5); cluster=kb:{uniform(10,1000,int)}" --observed observed.txt --out abc.txt [abc options] [simulation parameters]
+
+Rejection ABC; draws the parameters from the priors, simulates each draw as a separate run (in parallel) and computes the distance
+between the summary statistics of the last generation (see --file-sumstats; averaged over the replicates that did not fail) and the observed statistics.
+Each draw is written to the output with the drawn parameters, the statistics, the distance, whether it was accepted and its status;
+draws whose simulation failed (e.g. all replicates lost the TE) have the status 'failed' and are not accepted.
+
+ABC options:
+  --priors string     the priors of the parameters separated by ';'; uniform(min,max) or loguniform(min,max), optionally rounded
+                      to integers, e.g. uniform(10,1000,int); a template in which each '{}' is replaced by the drawn value may
+                      enclose the prior in braces, e.g. cluster=kb:{uniform(10,1000,int)},{} for two chromosomes
+  --observed string   file with the observed summary statistics; one 'name value' per line (e.g. 'avtes 35.2'); the names are
+                      `+strings.Join(popgen.GetSummaryStatisticNames(), ", ")+`
+  --draws int         number of draws from the priors (default 1000)
+  --tolerance float   draws with a distance smaller or equal to the tolerance are accepted (default 0.1); the distance is the
+                      root mean square of the relative differences between simulated and observed statistics
+  --threads int       number of simulations run in parallel (default: number of cores)
+  --seed int          seed of the ABC; the draws and the seeds of the runs are derived from it
+  --out string        mandatory; output file of the draws
+  --restart           continue an interrupted ABC; draws already present in --out are skipped (use the same --seed to reproduce the draws)
+
+All other parameters (e.g. --N 1000 --gen 5000 --genome MB:2 --rr 4 --basepop 100 --rep 3) are passed to each simulation;
+the output files (--file-*) are specific to each draw, e.g. --file-mhp mhp.txt yields mhp.draw1.txt, mhp.draw2.txt, ..`)
+}
+
+/*
+Run an ABC; the arguments are the command line parameters following the 'abc' subcommand;
+missing or invalid parameters of the ABC yield an error (after the usage is printed)
+*/
+func Run(args []string) error {
+	opts, passthrough := runner.SplitArgs(args, abcFlags)
+	if _, ok := opts["help"]; ok {
+		PrintUsage()
+		return nil
+	}
+	if opts["priors"] == "" || opts["observed"] == "" || opts["out"] == "" {
+		PrintUsage()
+		return fmt.Errorf("Provide suitable priors --priors, observed statistics --observed and an output file --out")
+	}
+	a := &ABC{priors: ParsePriors(opts["priors"]), args: passthrough, draws: 1000, tolerance: 0.1, threads: int64(runtime.NumCPU()), seed: time.Now().UnixNano()}
+	a.observed, a.statnames = loadObserved(opts["observed"])
+	var err error
+	if v, ok := opts["draws"]; ok {
+		if a.draws, err = strconv.ParseInt(v, 10, 64); err != nil || a.draws < 1 {
+			PrintUsage()
+			return fmt.Errorf("Provide a suitable number of draws --draws; must be larger or equal to 1")
+		}
+	}
+	if v, ok := opts["tolerance"]; ok {
+		if a.tolerance, err = strconv.ParseFloat(v, 64); err != nil || a.tolerance < 0.0 {
+			PrintUsage()
+			return fmt.Errorf("Provide a suitable tolerance --tolerance; must be larger or equal to 0.0")
+		}
+	}
+	if v, ok := opts["threads"]; ok {
+		if a.threads, err = strconv.ParseInt(v, 10, 64); err != nil || a.threads < 1 {
+			PrintUsage()
+			return fmt.Errorf("Provide a suitable number of threads --threads; must be larger or equal to 1")
+		}
+	}
+	if v, ok := opts["seed"]; ok {
+		if a.seed, err = strconv.ParseInt(v, 10, 64); err != nil {
+			PrintUsage()
+			return fmt.Errorf("Provide a suitable seed --seed")
+		}
+	}
+	for _, arg := range passthrough {
+		if arg == "--silent" || arg == "-silent" {
+			util.InvadeLogger.SetOutput(ioutil.Discard)
+		}
+	}
+
+	done := make(map[int64]bool)
+	hasHeader := false
+	if opts["restart"] == "true" {
+		done, hasHeader = a.loadCompleted(opts["out"])
+		a.out = writer.AppendOutput(opts["out"])
+		if hasHeader && !endsWithNewline(opts["out"]) {
+			a.out.WriteString("\n") // a truncated line of an interrupted ABC is skipped
+		}
+		util.InvadeLogger.Printf("ABC: restart; %d of %d draws are already completed", len(done), a.draws)
+	} else {
+		a.out = writer.CreateOutput(opts["out"])
+	}
+	if !hasHeader {
+		a.out.WriteString(strings.Join(a.getHeader(), "\t") + "\n")
+	}
+	defer a.out.Close()
+	a.run(done)
+	return nil
+}
+
+/*
+Parse the priors, e.g. "u=loguniform(0.001,0.5); cluster=kb:{uniform(10,1000,int)},{}"
+*/
+func ParsePriors(priors string) []Prior {
+	toret := []Prior{}
+	unique := make(map[string]bool)
+	for _, entry := range strings.Split(priors, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tmp := strings.SplitN(entry, "=", 2)
+		name := strings.TrimLeft(strings.TrimSpace(tmp[0]), "-")
+		if len(tmp) != 2 || name == "" {
+			panic(fmt.Sprintf("Invalid prior %s; must be name=prior, e.g. u=loguniform(0.001,0.5)", entry))
+		}
+		if unique[name] {
+			panic(fmt.Sprintf("Invalid priors; parameter %s is provided multiple times", name))
+		}
+		if _, ok := abcFlags[name]; ok {
+			panic(fmt.Sprintf("Invalid priors; %s is a parameter of the ABC", name))
+		}
+		unique[name] = true
+		toret = append(toret, parsePrior(name, strings.TrimSpace(tmp[1])))
+	}
+	if len(toret) == 0 {
+		panic(fmt.Sprintf("Invalid priors %s; no parameters", priors))
+	}
+	return toret
+}
+
+func parsePrior(name string, s string) Prior {
+	template := "{}"
+	dist := s
+	if start := strings.Index(s, "{"); start >= 0 && strings.Contains(s, "(") {
+		end := strings.Index(s[start:], "}")
+		if end < 0 {
+			panic(fmt.Sprintf("Invalid prior %s; missing '}'", s))
+		}
+		dist = s[start+1 : start+end]
+		template = s[:start] + "{}" + s[start+end+1:]
+	}
+	m := priorValue.FindStringSubmatch(strings.TrimSpace(dist))
+	if m == nil {
+		panic(fmt.Sprintf("Invalid prior %s; e.g. uniform(0.0,0.1), loguniform(0.001,0.5) or uniform(10,1000,int)", s))
+	}
+	min, err1 := strconv.ParseFloat(m[2], 64)
+	max, err2 := strconv.ParseFloat(m[3], 64)
+	if err1 != nil || err2 != nil || max < min {
+		panic(fmt.Sprintf("Invalid prior %s; bounds must be numbers with min <= max", s))
+	}
+	if m[1] == PRIORLOGUNIFORM && min <= 0.0 {
+		panic(fmt.Sprintf("Invalid prior %s; bounds of a log-uniform prior must be larger than 0", s))
+	}
+	return Prior{Name: name, Distribution: m[1], Min: min, Max: max, Integer: m[4] != "", template: template}
+}
+
+/*
+Draw a value from the prior
+*/
+func (p Prior) draw(rng *rand.Rand) float64 {
+	var v float64
+	if p.Distribution == PRIORLOGUNIFORM {
+		v = math.Exp(math.Log(p.Min) + rng.Float64()*(math.Log(p.Max)-math.Log(p.Min)))
+	} else {
+		v = p.Min + rng.Float64()*(p.Max-p.Min)
+	}
+	if p.Integer {
+		v = math.Round(v)
+	}
+	return v
+}
+
+/*
+The value of the command line parameter, i.e. the drawn value inserted into the template
+*/
+func (p Prior) format(v float64) string {
+	return strings.ReplaceAll(p.template, "{}", strconv.FormatFloat(v, 'g', -1, 64))
+}
+
+/*
+Load the observed summary statistics; one 'name value' per line, lines starting with '#' are ignored
+*/
+func loadObserved(file string) (map[string]float64, []string) {
+	f, err := os.Open(file)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	known := make(map[string]bool)
+	for _, n := range popgen.GetSummaryStatisticNames() {
+		known[n] = true
+	}
+	observed := make(map[string]float64)
+	scanner := bufio.NewScanner(f)
+	linenumber := 0
+	for scanner.Scan() {
+		linenumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tmp := strings.Fields(line)
+		if len(tmp) != 2 {
+			panic(fmt.Sprintf("Invalid observed statistics in line %d of %s; must be 'name value'", linenumber, file))
+		}
+		v, err := strconv.ParseFloat(tmp[1], 64)
+		if err != nil || !known[tmp[0]] {
+			panic(fmt.Sprintf("Invalid observed statistic %s in line %d of %s; known statistics are %v", line, linenumber, file, popgen.GetSummaryStatisticNames()))
+		}
+		observed[tmp[0]] = v
+	}
+	if len(observed) == 0 {
+		panic(fmt.Sprintf("No observed statistics in %s", file))
+	}
+	names := []string{}
+	for _, n := range popgen.GetSummaryStatisticNames() {
+		if _, ok := observed[n]; ok {
+			names = append(names, n)
+		}
+	}
+	return observed, names
+}
+
+func (a *ABC) getHeader() []string {
+	header := []string{"draw", "seed"}
+	for _, p := range a.priors {
+		header = append(header, p.Name)
+	}
+	header = append(header, a.statnames...)
+	return append(header, "distance", "accepted", "status")
+}
+
+/*
+The draws which are already present in an output file (restart) and whether the file has a header; the header must match
+*/
+func (a *ABC) loadCompleted(file string) (map[int64]bool, bool) {
+	done := make(map[int64]bool)
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return done, false
+	} else if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(file, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err == io.EOF {
+			return done, false
+		} else if err != nil {
+			panic(err)
+		}
+		r = gz
+	}
+	scanner := bufio.NewScanner(r)
+	header := strings.Join(a.getHeader(), "\t")
+	first := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		if first {
+			if line != header {
+				panic(fmt.Sprintf("Can not restart the ABC; the header of %s does not match the priors and observed statistics", file))
+			}
+			first = false
+			continue
+		}
+		tmp := strings.Split(line, "\t")
+		index, err := strconv.ParseInt(tmp[0], 10, 64)
+		if err != nil || len(tmp) != len(a.getHeader()) {
+			continue // e.g. a truncated last line of an interrupted ABC
+		}
+		done[index-1] = true
+	}
+	return done, !first
+}
+
+func endsWithNewline(file string) bool {
+	if strings.HasSuffix(file, ".gz") {
+		return true // lines are completed with the flushes of the gzip stream
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		panic(err)
+	}
+	return len(content) == 0 || content[len(content)-1] == '\n'
+}
+
+/*
+Get a draw from the priors; each draw has its own random number generator, thus the draws are independent of the number of threads and of restarts
+*/
+func (a *ABC) getDraw(index int64) Draw {
+	seed := util.DeriveSeed(a.seed, index)
+	rng := rand.New(rand.NewSource(seed))
+	values := make([]float64, len(a.priors))
+	for i, p := range a.priors {
+		values[i] = p.draw(rng)
+	}
+	return Draw{Index: index, Values: values, Seed: util.DeriveSeed(seed, 0)}
+}
+
+/*
+Simulate all draws which are not yet completed in parallel; the results are written in the order of the draws
+*/
+func (a *ABC) run(done map[int64]bool) {
+	exe, err := os.Executable()
+	if err != nil {
+		panic(err)
+	}
+	tmpdir, err := ioutil.TempDir("", "invade-abc")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	todo := []int64{}
+	for i := int64(0); i < a.draws; i++ {
+		if !done[i] {
+			todo = append(todo, i)
+		}
+	}
+	util.InvadeLogger.Printf("ABC: %d draws of %d parameters, %d in parallel, seed %d", len(todo), len(a.priors), a.threads, a.seed)
+	jobs := make(chan Draw)
+	results := make(chan drawResult)
+	var wg sync.WaitGroup
+	for t := int64(0); t < a.threads; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range jobs {
+				results <- a.simulate(exe, tmpdir, d)
+			}
+		}()
+	}
+	go func() {
+		for _, i := range todo {
+			jobs <- a.getDraw(i)
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := make(map[int64]drawResult)
+	next := 0
+	accepted, failed := 0, 0
+	for r := range results {
+		pending[r.draw.Index] = r
+		for next < len(todo) {
+			res, ok := pending[todo[next]]
+			if !ok {
+				break
+			}
+			delete(pending, todo[next])
+			next++
+			isAccepted := false
+			if res.err != nil {
+				failed++
+				util.InvadeLogger.Printf("ABC: draw %d (%s) failed: %v", res.draw.Index+1, a.formatDraw(res.draw), res.err)
+			} else if isAccepted = res.distance <= a.tolerance; isAccepted {
+				accepted++
+			}
+			a.out.WriteString(a.formatResult(res, isAccepted) + "\n")
+			a.out.Flush() // completed draws are kept if the ABC is interrupted
+		}
+	}
+	util.InvadeLogger.Printf("ABC: %d of %d draws accepted; %d failed (status 'failed' in the output)", accepted, len(todo), failed)
+}
+
+func (a *ABC) formatDraw(d Draw) string {
+	tmp := []string{}
+	for i, p := range a.priors {
+		tmp = append(tmp, p.Name+"="+p.format(d.Values[i]))
+	}
+	return strings.Join(tmp, " ")
+}
+
+func (a *ABC) formatResult(res drawResult, accepted bool) string {
+	values := []string{fmt.Sprintf("%d", res.draw.Index+1), fmt.Sprintf("%d", res.draw.Seed)}
+	for _, v := range res.draw.Values {
+		values = append(values, strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	if res.err != nil {
+		// a failed draw has neither statistics nor a distance
+		for range a.statnames {
+			values = append(values, "NA")
+		}
+		return strings.Join(append(values, "NA", "0", "failed"), "\t")
+	}
+	for _, s := range res.stats {
+		values = append(values, fmt.Sprintf("%f", s))
+	}
+	acc := "0"
+	if accepted {
+		acc = "1"
+	}
+	return strings.Join(append(values, fmt.Sprintf("%f", res.distance), acc, "ok"), "\t")
+}
+
+/*
+Simulate a draw and compute the distance between the simulated and the observed statistics
+*/
+func (a *ABC) simulate(exe string, tmpdir string, d Draw) drawResult {
+	file := filepath.Join(tmpdir, fmt.Sprintf("draw%d.sumstats.txt", d.Index+1))
+	defer os.Remove(file)
+	args := runner.TagOutputFiles(a.args, fmt.Sprintf("draw%d", d.Index+1))
+	for i, p := range a.priors {
+		args = append(args, "--"+p.Name, p.format(d.Values[i]))
+	}
+	args = append(args, "--seed", fmt.Sprintf("%d", d.Seed), "--silent", "--file-sumstats", file, "--file-main", os.DevNull)
+	if _, err := runner.RunSimulation(exe, args); err != nil {
+		return drawResult{draw: d, err: err}
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return drawResult{draw: d, err: err}
+	}
+	stats, err := a.averageStatistics(runner.SplitLines(string(content)))
+	if err != nil {
+		return drawResult{draw: d, err: err}
+	}
+	return drawResult{draw: d, stats: stats, distance: getDistance(stats, a.getObservedValues())}
+}
+
+/*
+Average the statistics of the observed file over the replicates of a simulation (lines of --file-sumstats including the header);
+failed replicates (e.g. loss of the TE) are excluded, the draw fails if all replicates failed
+*/
+func (a *ABC) averageStatistics(lines []string) ([]float64, error) {
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("no summary statistics")
+	}
+	columns := make(map[string]int)
+	for i, c := range strings.Split(lines[0], "\t") {
+		columns[c] = i
+	}
+	sums := make([]float64, len(a.statnames))
+	count := 0
+	for _, line := range lines[1:] {
+		tmp := strings.Split(line, "\t")
+		if strings.HasPrefix(tmp[columns["popstat"]], "fail") {
+			continue
+		}
+		for i, n := range a.statnames {
+			v, err := strconv.ParseFloat(tmp[columns[n]], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid summary statistic %s", tmp[columns[n]])
+			}
+			sums[i] += v
+		}
+		count++
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("all %d replicates failed", len(lines)-1)
+	}
+	for i := range sums {
+		sums[i] /= float64(count)
+	}
+	return sums, nil
+}
+
+func (a *ABC) getObservedValues() []float64 {
+	toret := make([]float64, len(a.statnames))
+	for i, n := range a.statnames {
+		toret[i] = a.observed[n]
+	}
+	return toret
+}
+
+/*
+Distance between simulated and observed statistics; the root mean square of the differences relative to the observed values
+(absolute differences for observed values of zero)
+*/
+func getDistance(simulated []float64, observed []float64) float64 {
+	sum := 0.0
+	for i := range observed {
+		d := simulated[i] - observed[i]
+		if observed[i] != 0.0 {
+			d /= math.Abs(observed[i])
+		}
+		sum += d * d
+	}
+	return math.Sqrt(sum / float64(len(observed)))
+}
@@ -0,0 +1,124 @@
+package abc
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParsePriors(t *testing.T) {
+	priors := ParsePriors("u=loguniform(0.001,0.5); cluster=kb:{uniform(10,1000,int)},{}; --x=uniform(0,0.1)")
+	var tests = []struct {
+		name    string
+		dist    string
+		min     float64
+		max     float64
+		integer bool
+		format  string // formatted value of 20
+	}{
+		{name: "u", dist: PRIORLOGUNIFORM, min: 0.001, max: 0.5, integer: false, format: "20"},
+		{name: "cluster", dist: PRIORUNIFORM, min: 10, max: 1000, integer: true, format: "kb:20,20"},
+		{name: "x", dist: PRIORUNIFORM, min: 0, max: 0.1, integer: false, format: "20"},
+	}
+	if len(priors) != len(tests) {
+		t.Fatalf("Incorrect number of priors; got %d", len(priors))
+	}
+	for i, test := range tests {
+		p := priors[i]
+		if p.Name != test.name || p.Distribution != test.dist || p.Min != test.min || p.Max != test.max || p.Integer != test.integer {
+			t.Errorf("Incorrect prior %s; got %+v", test.name, p)
+		}
+		if got := p.format(20); got != test.format {
+			t.Errorf("Incorrect template of prior %s; want %s, got %s", test.name, test.format, got)
+		}
+	}
+}
+
+func TestPriorDraw(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	priors := ParsePriors("a=loguniform(0.01,100); b=uniform(5,10,int)")
+	below := 0
+	for i := 0; i < 10000; i++ {
+		a := priors[0].draw(rng)
+		b := priors[1].draw(rng)
+		if a < 0.01 || a > 100 || b < 5 || b > 10 || b != math.Round(b) {
+			t.Fatalf("Draw outside of the priors; got %f %f", a, b)
+		}
+		if a < 1.0 {
+			below++
+		}
+	}
+	// log-uniform; half of the draws are below 1.0
+	if below < 4700 || below > 5300 {
+		t.Errorf("Incorrect log-uniform draws; %d of 10000 below 1.0", below)
+	}
+}
+
+func TestGetDistance(t *testing.T) {
+	var tests = []struct {
+		sim  []float64
+		obs  []float64
+		want float64
+	}{
+		{sim: []float64{10, 0.5}, obs: []float64{10, 0.5}, want: 0.0},
+		{sim: []float64{12}, obs: []float64{10}, want: 0.2},
+		{sim: []float64{12, 0.5}, obs: []float64{10, 0.0}, want: math.Sqrt((0.04 + 0.25) / 2.0)},
+	}
+	for _, test := range tests {
+		if got := getDistance(test.sim, test.obs); math.Abs(got-test.want) > 0.000001 {
+			t.Errorf("Incorrect distance between %v and %v; want %f, got %f", test.sim, test.obs, test.want, got)
+		}
+	}
+}
+
+func TestDrawsAndRestart(t *testing.T) {
+	a := &ABC{priors: ParsePriors("u=uniform(0,1)"), seed: 7, statnames: []string{"avtes"}}
+	// draws are reproducible and independent of their order
+	if a.getDraw(3).Values[0] != a.getDraw(3).Values[0] || a.getDraw(3).Values[0] == a.getDraw(4).Values[0] {
+		t.Errorf("Draws are not reproducible")
+	}
+	file := filepath.Join(t.TempDir(), "abc.txt")
+	// failed draws are written with their status and are completed as well
+	failed := a.formatResult(drawResult{draw: Draw{Index: 5, Values: []float64{0.5}, Seed: 9}, err: fmt.Errorf("all 3 replicates failed")}, false)
+	if failed != "6\t9\t0.5\tNA\tNA\t0\tfailed" {
+		t.Errorf("Incorrect line of a failed draw; got %s", failed)
+	}
+	os.WriteFile(file, []byte("draw\tseed\tu\tavtes\tdistance\taccepted\tstatus\n1\t5\t0.1\t3.0\t0.2\t0\tok\n3\t5\t0.1\t3.0\t0.2\t1\tok\n"+failed+"\n4\t5\t0.1"), 0644)
+	done, hasHeader := a.loadCompleted(file)
+	if !hasHeader || len(done) != 3 || !done[0] || !done[2] || !done[5] {
+		t.Errorf("Incorrect completed draws; got %v", done)
+	}
+	if endsWithNewline(file) {
+		t.Errorf("Truncated line was not detected")
+	}
+}
+
+func TestAverageStatistics(t *testing.T) {
+	a := &ABC{statnames: []string{"avtes", "fixed"}}
+	header := "rep\tgen\tpopstat\tavtes\tavcli\tfixed"
+	var tests = []struct {
+		lines   []string
+		want    []float64
+		wanterr bool
+	}{
+		{lines: []string{header, "1\t100\tok\t10.0\t1.0\t2", "2\t100\tok\t20.0\t1.0\t4"}, want: []float64{15.0, 3.0}},
+		{lines: []string{header, "1\t100\tstop\t10.0\t1.0\t2", "2\t35\tfail-0\t0.0\t0.0\t0", "3\t100\tok\t20.0\t1.0\t4"}, want: []float64{15.0, 3.0}},
+		{lines: []string{header, "1\t35\tfail-0\t0.0\t0.0\t0", "2\t60\tfail-w\t50.0\t1.0\t0"}, wanterr: true},
+		{lines: []string{header}, wanterr: true},
+	}
+	for _, test := range tests {
+		got, err := a.averageStatistics(test.lines)
+		if test.wanterr {
+			if err == nil {
+				t.Errorf("Missing error of %v; got %v", test.lines, got)
+			}
+			continue
+		}
+		if err != nil || got[0] != test.want[0] || got[1] != test.want[1] {
+			t.Errorf("Incorrect average statistics of %v; want %v, got %v %v", test.lines, test.want, got, err)
+		}
+	}
+}
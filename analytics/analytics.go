@@ -0,0 +1,114 @@
+/*
+Analytical predictions of the trap model for the resolved environment; reported with the simulations,
+e.g. to see how far a simulation deviates from theory
+*/
+package analytics
+
+import (
+	"fmt"
+	"invade/env"
+	"invade/fly"
+	"math"
+)
+
+/*
+The fraction of the flies with piRNAs at which the invasion is silenced, i.e. the start of the shotgun phase
+*/
+const SILENCINGFREQUENCY float64 = 0.99
+
+/*
+The maximum number of generations considered for the expected time to the first cluster insertion
+*/
+const MAXGENERATIONS int64 = 1000000
+
+/*
+The predictions of the trap model;
+the invasion stops at about 1/(cluster fraction) insertions per haploid genome, i.e. when a haploid genome carries on average one cluster insertion;
+undefined values (e.g. without clusters) are NaN
+*/
+type Predictions struct {
+	ClusterFraction     float64 // fraction of the genome covered by piRNA clusters
+	HaploidInsertions   float64 // insertions per haploid genome at the equilibrium; 1/fraction
+	DiploidInsertions   float64 // insertions per fly at the equilibrium; 2/fraction
+	SilencingInsertions float64 // insertions per fly when 99% of the flies carry a cluster insertion (Poisson distributed cluster insertions)
+	GenFirstCluster     float64 // expected generation of the first cluster insertion
+	FixationProbability float64 // fixation probability of a novel neutral insertion; 1/(2N)
+	GenFixation         float64 // expected number of generations until a neutral insertion is fixed, given it is fixed; 4N
+}
+
+var predictions *Predictions
+
+/*
+Setup the predictions for the resolved environment; the transposition rate u, the population size and the base population (e.g. of the first replicate)
+*/
+func SetupPredictions(u float64, popsize int64, basepop *fly.Population) *Predictions {
+	predictions = Predict(env.GetClusterFraction(), u, popsize, basepop.GetAverageInsertions(), basepop.GetAverageClusterInsertions())
+	return predictions
+}
+
+/*
+The predictions; nil if not set up
+*/
+func GetPredictions() *Predictions {
+	return predictions
+}
+
+/*
+Predict the invasion given the cluster fraction, the transposition rate u, the population size and the average number of insertions and cluster insertions per fly in the base population
+*/
+func Predict(fraction float64, u float64, popsize int64, insertions float64, cluinsertions float64) *Predictions {
+	p := &Predictions{ClusterFraction: fraction, HaploidInsertions: math.NaN(), DiploidInsertions: math.NaN(), SilencingInsertions: math.NaN(),
+		FixationProbability: 1.0 / float64(2*popsize), GenFixation: 4.0 * float64(popsize)}
+	if fraction > 0.0 {
+		p.HaploidInsertions = 1.0 / fraction
+		p.DiploidInsertions = 2.0 / fraction
+		p.SilencingInsertions = -math.Log(1.0-SILENCINGFREQUENCY) / fraction
+	}
+	p.GenFirstCluster = getGenFirstCluster(fraction, u, popsize, insertions, cluinsertions)
+	return p
+}
+
+/*
+The expected generation of the first cluster insertion;
+the insertions of the population grow by the factor 1+u per generation (u novel insertions per insertion of a diploid),
+a novel insertion hits a cluster with the probability 'fraction';
+the cluster insertions are thus a Poisson process with the cumulative intensity L(t) = fraction * N * n0 * ((1+u)^t - 1)
+and the expected generation is the sum of P(T > t) = exp(-L(t))
+*/
+func getGenFirstCluster(fraction float64, u float64, popsize int64, insertions float64, cluinsertions float64) float64 {
+	if cluinsertions > 0.0 {
+		return 0.0
+	}
+	if fraction <= 0.0 || u <= 0.0 || insertions <= 0.0 {
+		return math.NaN()
+	}
+	rate := fraction * float64(popsize) * insertions
+	expected := 0.0
+	for t := int64(0); t < MAXGENERATIONS; t++ {
+		survival := math.Exp(-rate * (math.Pow(1.0+u, float64(t)) - 1.0))
+		if survival < 1e-12 {
+			return expected
+		}
+		expected += survival
+	}
+	return math.NaN()
+}
+
+/*
+The predictions as lines of text, e.g. for the header of the main table or --explain
+*/
+func (p *Predictions) GetDescription() []string {
+	return []string{
+		fmt.Sprintf("trap model: cluster fraction %.6f; equilibrium %s insertions per haploid genome, %s per fly; %s insertions per fly at silencing (%.0f%% of the flies with cluster insertions)",
+			p.ClusterFraction, formatPrediction(p.HaploidInsertions), formatPrediction(p.DiploidInsertions), formatPrediction(p.SilencingInsertions), SILENCINGFREQUENCY*100.0),
+		fmt.Sprintf("trap model: first cluster insertion expected at generation %s", formatPrediction(p.GenFirstCluster)),
+		fmt.Sprintf("neutral: fixation probability of a novel insertion %.6f, expected time to fixation %s generations", p.FixationProbability, formatPrediction(p.GenFixation)),
+	}
+}
+
+func formatPrediction(v float64) string {
+	if math.IsNaN(v) {
+		return "NA"
+	}
+	return fmt.Sprintf("%.2f", v)
+}
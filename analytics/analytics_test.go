@@ -0,0 +1,56 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPredict(test *testing.T) {
+	p := Predict(0.1, 0.1, 250, 2.0, 0.0)
+	var tests = []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"haploid insertions", p.HaploidInsertions, 10.0},
+		{"diploid insertions", p.DiploidInsertions, 20.0},
+		{"insertions at silencing", p.SilencingInsertions, math.Log(100.0) * 10.0},
+		{"fixation probability", p.FixationProbability, 0.002},
+		{"time to fixation", p.GenFixation, 1000.0},
+	}
+	for _, t := range tests {
+		if math.Abs(t.got-t.want) > 0.000001 {
+			test.Errorf("Incorrect prediction of the %s; want %f, got %f", t.name, t.want, t.got)
+		}
+	}
+	none := Predict(0.0, 0.1, 250, 2.0, 0.0)
+	if !math.IsNaN(none.DiploidInsertions) || !math.IsNaN(none.GenFirstCluster) {
+		test.Errorf("Predictions without clusters must be undefined; got %+v", none)
+	}
+}
+
+func TestGenFirstCluster(test *testing.T) {
+	var tests = []struct {
+		fraction      float64
+		u             float64
+		popsize       int64
+		insertions    float64
+		cluinsertions float64
+		want          float64
+	}{
+		{0.1, 1.0, 1, 1.0, 0.0, 3.412258}, // sum of exp(-0.1*(2^t-1))
+		{0.1, 1.0, 1, 1.0, 0.5, 0.0},      // cluster insertions in the base population
+		{0.1, 0.0, 1, 1.0, 0.0, math.NaN()},
+		{0.1, 1.0, 1, 0.0, 0.0, math.NaN()},
+	}
+	for _, t := range tests {
+		got := getGenFirstCluster(t.fraction, t.u, t.popsize, t.insertions, t.cluinsertions)
+		if math.IsNaN(t.want) != math.IsNaN(got) || (!math.IsNaN(t.want) && math.Abs(got-t.want) > 0.0001) {
+			test.Errorf("Incorrect generation of the first cluster insertion %+v; got %f", t, got)
+		}
+	}
+	// more insertions in the base population yield an earlier first cluster insertion
+	if getGenFirstCluster(0.01, 0.1, 100, 1.0, 0.0) <= getGenFirstCluster(0.01, 0.1, 100, 5.0, 0.0) {
+		test.Errorf("The first cluster insertion must be earlier with more insertions")
+	}
+}
@@ -260,31 +260,60 @@ func TestSeparateInsertions(t *testing.T) {
 	}
 }
 
-func TestGetNovelInsertionCount(t *testing.T) {
+func TestGetSilencedInsertionCount(t *testing.T) {
 	var tests = []struct {
-		tot   int64
-		matpi bool // are there piRNAS in the fly (irrespective of origin; i.e. cluster or paramutation)
-		jump  Jumper
-		want  float64
+		tot       int64
+		silencing float64 // level of silencing by piRNAs (irrespective of origin; i.e. cluster or paramutation)
+		jump      Jumper
+		want      float64
 	}{
-		{tot: 10, matpi: false, jump: Jumper{u: 0.1, uc: 0.0}, want: 1.0},
-		{tot: 10, matpi: true, jump: Jumper{u: 0.1, uc: 0.0}, want: 0.0},
-		{tot: 100, matpi: false, jump: Jumper{u: 0.1, uc: 0.0}, want: 10.0},
-		{tot: 100, matpi: true, jump: Jumper{u: 0.1, uc: 0.0}, want: 0.0},
-		{tot: 100, matpi: true, jump: Jumper{u: 0.1, uc: 0.01}, want: 1.0},
+		{tot: 10, silencing: 0.0, jump: Jumper{u: 0.1, uc: 0.0}, want: 1.0},
+		{tot: 10, silencing: 1.0, jump: Jumper{u: 0.1, uc: 0.0}, want: 0.0},
+		{tot: 100, silencing: 0.0, jump: Jumper{u: 0.1, uc: 0.0}, want: 10.0},
+		{tot: 100, silencing: 1.0, jump: Jumper{u: 0.1, uc: 0.0}, want: 0.0},
+		{tot: 100, silencing: 1.0, jump: Jumper{u: 0.1, uc: 0.01}, want: 1.0},
+		{tot: 100, silencing: 0.5, jump: Jumper{u: 0.1, uc: 0.0}, want: 5.0},
+		{tot: 100, silencing: 0.5, jump: Jumper{u: 0.1, uc: 0.01}, want: 5.5},
 	}
 
 	for _, test := range tests {
 		ju := test.jump
-		got := ju.getNovelInsertionCount(test.tot, test.matpi)
+		got := ju.getSilencedInsertionCount(test.tot, test.silencing)
 		dif := math.Abs(test.want - got)
 		if dif > 0.00001 {
-			t.Errorf("getNovelInsertionCount(); got %f wanted %f", got, test.want)
+			t.Errorf("getSilencedInsertionCount(); got %f wanted %f", got, test.want)
 		}
 
 	}
 }
 
+func TestStochasticGetSilencedTranspositionSites(t *testing.T) {
+	util.SetSeed(7)
+	SetJumper(0.1, 0.0)
+	env = Environment{
+		genome: newGenomicLandscape([]int64{10, 10}),
+	}
+	var tests = []struct {
+		silencing float64
+		factor    float64
+		min       int
+		max       int
+	}{
+		{silencing: 1.0, factor: 1.0, min: 0, max: 0},       // fully silenced; uc = 0
+		{silencing: 0.5, factor: 1.0, min: 2400, max: 2600}, // 1000 * 0.05*100 / 2
+		{silencing: 0.5, factor: 2.0, min: 4900, max: 5100}, // dysgenic; twice the rate
+	}
+	for _, test := range tests {
+		totcounter := 0
+		for i := 0; i < 1000; i++ {
+			totcounter += len(GetNewTranspositionSites(100, test.silencing, test.factor))
+		}
+		if totcounter < test.min || totcounter > test.max {
+			t.Errorf("GetNewTranspositionSites(100, %f, %f); invalid number of novel insertions %d, should be within %d-%d", test.silencing, test.factor, totcounter, test.min, test.max)
+		}
+	}
+}
+
 func TestStochasticGetNovelInsertionSites(test *testing.T) {
 	util.SetSeed(5)
 	SetJumper(0.1, 0.0)
@@ -297,7 +326,7 @@ func TestStochasticGetNovelInsertionSites(test *testing.T) {
 	totcounter := 0
 	for i := 0; i < 1000; i++ {
 
-		newsites := GetNewTranspositionSites(100, false)
+		newsites := GetNewTranspositionSites(100, 0.0, 1.0)
 		totcounter += len(newsites)
 		for _, n := range newsites {
 			sitecounter[n]++
@@ -457,3 +486,71 @@ func TestTranslateCoordinates(test *testing.T) {
 
 	}
 }
+
+func TestChromosomeNames(test *testing.T) {
+	SetupEnvironment([]int64{100, 200, 300}, nil, nil, nil, nil, []float64{4, 4, 4}, 0.1, 1000.0)
+	if GetChromosomeName(2) != "2" {
+		test.Errorf("Wrong default name of chromosome; want 2 got %s", GetChromosomeName(2))
+	}
+	SetupChromosomeNames([]string{"2L", "2R", "X"})
+	var tests = []struct {
+		pos      int64
+		wantname string
+		wantpos  int64
+	}{{pos: 0, wantname: "2L", wantpos: 1},
+		{pos: 99, wantname: "2L", wantpos: 100},
+		{pos: 100, wantname: "2R", wantpos: 1},
+		{pos: 599, wantname: "X", wantpos: 300},
+	}
+	for _, t := range tests {
+		chrm, pos := TranslateCoordinates(t.pos)
+		if name := GetChromosomeName(chrm); name != t.wantname || pos != t.wantpos {
+			test.Errorf("Wrong coordinates; want %s:%d got %s:%d", t.wantname, t.wantpos, name, pos)
+		}
+		if lin := GetLinearCoordinate(t.wantname, t.wantpos); lin != t.pos {
+			test.Errorf("Wrong linear coordinate of %s:%d; want %d got %d", t.wantname, t.wantpos, t.pos, lin)
+		}
+	}
+}
+
+func TestRecurrentSiteGenomeFraction(test *testing.T) {
+	var r = newRecurrentSite([]bool{false, true, true, false, false})
+	var tests = []struct {
+		size  int64
+		count int64 // number of recurrent sites
+	}{
+		{10, 4},
+		{12, 5},   // 1,2,6,7,11
+		{2, 1},    // 1
+		{101, 40}, // 100 full cycles; position 100 is not a recurrent site
+	}
+	for _, t := range tests {
+		if got := r.getGenomeFraction(t.size); math.Abs(got-float64(t.count)/float64(t.size)) > 0.000001 {
+			test.Errorf("getGenomeFraction(%d)=%f, want %d sites", t.size, got, t.count)
+		}
+	}
+	var none *RecurrentSites
+	if none.getGenomeFraction(10) != 0.0 {
+		test.Errorf("Nil recurrent sites cover a fraction of the genome")
+	}
+}
+
+func TestDescribeEnvironment(test *testing.T) {
+	SetupEnvironment([]int64{1000, 500}, []int64{100, 0}, []int64{0, 50}, nil, nil, []float64{4.0, 0.0}, 0.1, 1000)
+	lines := DescribeEnvironment()
+	want := map[string]bool{
+		"2\t500\t1000\t1000\t1499\t0.000000": true,
+		"cluster\t1\t1\t100\t0\t99\t100":     true,
+		"ref\t2\t451\t500\t1450\t1499\t50":   true,
+		"# trigger sites: none":              true,
+	}
+	for _, l := range lines {
+		delete(want, l)
+	}
+	if len(want) > 0 {
+		test.Errorf("Missing lines %v in the description of the environment %v", want, lines)
+	}
+	if GetClusterFraction() != 100.0/1500.0 {
+		test.Errorf("Incorrect cluster fraction %f", GetClusterFraction())
+	}
+}
@@ -0,0 +1,85 @@
+package env
+
+import (
+	"fmt"
+	"math"
+)
+
+/*
+A description of the environment built by SetupEnvironment, e.g. for --explain;
+the linear genome layout with the chromosome offsets and recombination lambdas, the piRNA clusters and reference regions in chromosome (1-based)
+and linear (0-based) coordinates and the fraction of the genome covered by clusters, trigger sites and paramutable sites
+*/
+func DescribeEnvironment() []string {
+	g := env.genome
+	lines := []string{fmt.Sprintf("# genome: %d chromosomes, %d bp", len(g.chrmSizes), g.totalGenome)}
+	lines = append(lines, "chrom\tlength\toffset\tlinear_start\tlinear_end\trec_lambda")
+	for i, gi := range g.intervals {
+		lambda := 0.0
+		if i < len(env.recombinationWindows) {
+			lambda = math.Max(env.recombinationWindows[i].lambda, 0.0) // no -0 without recombination
+		}
+		lines = append(lines, fmt.Sprintf("%s\t%d\t%d\t%d\t%d\t%.6f", g.names[i], gi.Length(), g.offsets[i], gi.Start, gi.End, lambda))
+	}
+	lines = append(lines, describeRegions("piRNA clusters", "cluster", env.clusters)...)
+	lines = append(lines, describeRegions("reference regions", "ref", env.refRegions)...)
+	lines = append(lines, describeRecurrentSites("trigger sites", env.triggers))
+	lines = append(lines, describeRecurrentSites("paramutable sites", env.paramutables))
+	return lines
+}
+
+func describeRegions(title string, kind string, regions RegionCollection) []string {
+	if len(regions) == 0 {
+		return []string{fmt.Sprintf("# %s: none", title)}
+	}
+	lines := []string{fmt.Sprintf("# %s: %d bp, %.6f of the genome", title, regions.Size(), float64(regions.Size())/float64(GetGenomeSize()))}
+	lines = append(lines, "region\tchrom\tstart\tend\tlinear_start\tlinear_end\tlength")
+	for _, gi := range regions {
+		if gi.Length() < 1 {
+			continue // e.g. a cluster of size 0 on a chromosome
+		}
+		chrnum, start := TranslateCoordinates(gi.Start)
+		_, end := TranslateCoordinates(gi.End)
+		lines = append(lines, fmt.Sprintf("%s\t%s\t%d\t%d\t%d\t%d\t%d", kind, GetChromosomeName(chrnum), start, end, gi.Start, gi.End, gi.Length()))
+	}
+	return lines
+}
+
+func describeRecurrentSites(title string, r *RecurrentSites) string {
+	if r == nil {
+		return fmt.Sprintf("# %s: none", title)
+	}
+	residuals := []int64{}
+	for i, s := range r.Sites {
+		if s {
+			residuals = append(residuals, int64(i))
+		}
+	}
+	return fmt.Sprintf("# %s: residuals %v of modulo %d, %.6f of the genome", title, residuals, r.Modulo, r.getGenomeFraction(GetGenomeSize()))
+}
+
+/*
+The fraction of the positions of a genome (0 to size-1) which are recurrent sites
+*/
+func (r *RecurrentSites) getGenomeFraction(size int64) float64 {
+	if r == nil || size == 0 {
+		return 0.0
+	}
+	var count int64
+	for i, s := range r.Sites {
+		if s {
+			count += size / r.Modulo // full cycles
+			if int64(i) < size%r.Modulo {
+				count++
+			}
+		}
+	}
+	return float64(count) / float64(size)
+}
+
+/*
+The fraction of the genome covered by piRNA clusters
+*/
+func GetClusterFraction() float64 {
+	return float64(env.clusters.Size()) / float64(GetGenomeSize())
+}
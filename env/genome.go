@@ -62,7 +62,11 @@ func newGenomicLandscape(lens []int64) *GenomicLandscape {
 		chrmSizes = append(chrmSizes, len)
 		currentOffset = end + 1
 	}
-	return &GenomicLandscape{offsets: offsets, chrmSizes: chrmSizes, intervals: intervals, totalGenome: totGenome}
+	names := make([]string, len(lens))
+	for i := range lens {
+		names[i] = fmt.Sprintf("%d", i+1) // chromosomes are numbered by default
+	}
+	return &GenomicLandscape{offsets: offsets, chrmSizes: chrmSizes, intervals: intervals, totalGenome: totGenome, names: names}
 }
 
 /*
@@ -75,6 +79,69 @@ type GenomicLandscape struct {
 	chrmSizes   []int64
 	intervals   []GenomicInterval
 	totalGenome int64
+	names       []string // names of the chromosomes; 1, 2, 3.. by default
+}
+
+/*
+Get the total size of the genome, i.e. the sum of all chromosome sizes
+*/
+func GetGenomeSize() int64 {
+	return env.genome.totalGenome
+}
+
+/*
+Get the sizes of the chromosomes, in the order of the chromosomes
+*/
+func GetChromosomeSizes() []int64 {
+	toret := make([]int64, len(env.genome.chrmSizes))
+	copy(toret, env.genome.chrmSizes)
+	return toret
+}
+
+/*
+Name the chromosomes (e.g. 2L, 2R, 3L, 3R, X); the names are used for the output
+*/
+func SetupChromosomeNames(names []string) {
+	if len(names) != len(env.genome.chrmSizes) {
+		panic(fmt.Sprintf("invalid chromosome names %v; the number of names must match the number of chromosomes", names))
+	}
+	unique := make(map[string]bool)
+	for _, n := range names {
+		if n == "" || unique[n] {
+			panic(fmt.Sprintf("invalid chromosome names %v; names must be unique and not empty", names))
+		}
+		unique[n] = true
+	}
+	env.genome.names = append([]string{}, names...)
+}
+
+func GetChromosomeNames() []string {
+	return append([]string{}, env.genome.names...)
+}
+
+/*
+Get the name of a chromosome given its 1-based number (see TranslateCoordinates)
+*/
+func GetChromosomeName(chrnum int64) string {
+	if chrnum < 1 || chrnum > int64(len(env.genome.names)) {
+		panic(fmt.Sprintf("invalid chromosome number %d", chrnum))
+	}
+	return env.genome.names[chrnum-1]
+}
+
+/*
+Translate a chromosome name and a 1-based position on the chromosome into the 0-based linear coordinate (inverse of TranslateCoordinates)
+*/
+func GetLinearCoordinate(chrm string, chrpos int64) int64 {
+	for i, n := range env.genome.names {
+		if n == chrm {
+			if chrpos < 1 || chrpos > env.genome.chrmSizes[i] {
+				panic(fmt.Sprintf("invalid position %d; outside of chromosome %s", chrpos, chrm))
+			}
+			return env.genome.offsets[i] + chrpos - 1
+		}
+	}
+	panic(fmt.Sprintf("unknown chromosome %s", chrm))
 }
 
 /*
@@ -17,16 +17,11 @@ func SetJumper(u float64, uc float64) {
 }
 
 /*
-	Get the average number of transposition events for a DIPLOID.
-	For HAPLOIDS divide by two.
-	Considers u and uc, where u is the regular transposition rate and uc the transposition rate with i) a cluster insertion or ii) maternal piRNAs and paramutable site
-
+	Get the average number of transposition events for a DIPLOID with a given level of silencing by piRNAs;
+	The transposition rate is interpolated between u (silencing = 0.0) and uc (silencing = 1.0)
 */
-func (j *Jumper) getNovelInsertionCount(totalCount int64, pirna bool) float64 {
-	activeu := j.u
-	if pirna {
-		activeu = j.uc
-	}
+func (j *Jumper) getSilencedInsertionCount(totalCount int64, silencing float64) float64 {
+	activeu := j.u - (j.u-j.uc)*silencing
 	lambda := activeu * float64(totalCount)
 	return lambda
 }
@@ -34,10 +29,12 @@ func (j *Jumper) getNovelInsertionCount(totalCount int64, pirna bool) float64 {
 /*
 Get the positions of novel insertions for a haploid gamete; Input parameters are for a DIPLOID fly!
 Number of required insertions is then divided by two to obtain estimates for haploid genomes.
+The silencing by piRNAs ranges from 0.0 (transposition rate u) to 1.0 (transposition rate uc).
+The factor scales the transposition rate (e.g. elevated transposition in dysgenic flies); 1.0 for the regular rate.
 returns a list of novel insertion sites; not unique, may contain same site twice
 */
-func GetNewTranspositionSites(totalCount int64, pirna bool) []int64 {
-	newcountAverageDiploid := jump.getNovelInsertionCount(totalCount, pirna)
+func GetNewTranspositionSites(totalCount int64, silencing float64, factor float64) []int64 {
+	newcountAverageDiploid := jump.getSilencedInsertionCount(totalCount, silencing) * factor
 	newcountAverageHaploid := float64(newcountAverageDiploid) / 2.0 // is this valid? see below
 	newcountHaploid := util.Poisson(newcountAverageHaploid)
 	toret := make([]int64, newcountHaploid)
@@ -52,3 +49,10 @@ func GetNewTranspositionSites(totalCount int64, pirna bool) []int64 {
 		 then the combined process (the superposition of the component processes) is a Poisson process with rate ∑iλi
 	*/
 }
+
+/*
+The expected number of novel insertions in a haploid gamete of a fly with the given number of insertions (DIPLOID) and level of silencing by piRNAs
+*/
+func GetExpectedNewInsertions(insertions float64, silencing float64) float64 {
+	return jump.getSilencedInsertionCount(1, silencing) * insertions / 2.0
+}
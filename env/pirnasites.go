@@ -3,6 +3,7 @@ package env
 import (
 	"fmt"
 	"invade/util"
+	"math/rand"
 )
 
 type RegionCollection []GenomicInterval
@@ -126,6 +127,31 @@ func IsClusterInsertion(position int64) bool {
 	return env.clusters.IsInRegion(position)
 }
 
+/*
+Total size of the piRNA clusters
+*/
+func GetClusterSize() int64 {
+	return env.clusters.Size()
+}
+
+/*
+Get a random insertion site within the piRNA clusters; each cluster position has the same probability
+*/
+func GetRandomClusterSite() int64 {
+	size := env.clusters.Size()
+	if size == 0 {
+		panic("invalid request for a cluster insertion; no piRNA clusters were provided")
+	}
+	r := rand.Int63n(size)
+	for _, gi := range env.clusters {
+		if r < gi.Length() {
+			return gi.Start + r
+		}
+		r -= gi.Length()
+	}
+	panic("invalid cluster position")
+}
+
 func IsReferenceInsertion(position int64) bool {
 	return env.refRegions.IsInRegion(position)
 }
@@ -0,0 +1,54 @@
+package fly
+
+import (
+	"invade/util"
+)
+
+/*
+Hybrid dysgenesis;
+offspring of a naive mother (no maternal piRNAs) and a father carrying TEs are dysgenic.
+Dysgenic flies may have an elevated transposition rate and may be sterile (gonadal atrophy)
+*/
+type Dysgenesis struct {
+	uFactor   float64 // multiplier of the transposition rate in dysgenic flies; 1.0 = no effect
+	sterility float64 // probability that a dysgenic fly is sterile; 0.0 = no effect
+}
+
+var dysgen = Dysgenesis{uFactor: 1.0, sterility: 0.0}
+
+func SetupDysgenesis(uFactor float64, sterility float64) {
+	if uFactor < 0.0 {
+		panic("invalid transposition rate multiplier for dysgenic flies; must be larger or equal to 0.0")
+	}
+	if sterility < 0.0 || sterility > 1.0 {
+		panic("invalid sterility of dysgenic flies; must be between 0.0 and 1.0")
+	}
+	dysgen = Dysgenesis{uFactor: uFactor, sterility: sterility}
+}
+
+/*
+A cross is dysgenic if the mother has no maternal piRNAs and the father carries at least one TE insertion
+*/
+func isDysgenicCross(female *Fly, male *Fly) bool {
+	return female.Matpirna == 0 && male.FlyStat.CountTotal > 0
+}
+
+/*
+Turn a fly into a dysgenic fly; a sterile fly will never mate (see getMatingWeight), its fitness remains unchanged
+*/
+func (d *Dysgenesis) makeDysgenic(f *Fly) {
+	f.Dysgenic = true
+	if util.Bernoulli(d.sterility) {
+		f.Sterile = true
+	}
+}
+
+/*
+Multiplier of the transposition rate of a fly; only dysgenic flies may have an elevated transposition rate
+*/
+func (d *Dysgenesis) getTranspositionFactor(f *Fly) float64 {
+	if f.Dysgenic {
+		return d.uFactor
+	}
+	return 1.0
+}
@@ -24,14 +24,39 @@ const (
 )
 
 type Fly struct {
-	FlyNumber int64 // each fly has a number; starting at 1
-	Hap1      []int64
-	Hap2      []int64
-	Matpirna  int64 // number of the fly that triggered the maternal piRNAs; allows to identify soft sweeps from recurrent mutations!
-	Sex       Sex
-	Fitness   float64
-	FlyStat   *FlyStatistic
+	FlyNumber    int64 // each fly has a number; starting at 1
+	Hap1         []int64
+	Hap2         []int64
+	Matpirna     int64   // number of the fly that triggered the maternal piRNAs; allows to identify soft sweeps from recurrent mutations!
+	Paramutated  bool    // piRNAs are produced by paramutated loci
+	Pirna        float64 // piRNA level; maternally deposited and zygotically produced piRNAs
+	PirnaZygotic float64 // piRNA level; solely the zygotically produced piRNAs
+	Zygotic      bool    // piRNAs are solely produced zygotically, i.e. the mother had no piRNAs
+	Sex          Sex
+	Fitness      float64
+	FlyStat      *FlyStatistic
+	Dysgenic     bool  // offspring of a mother without piRNAs and a father with TEs (hybrid dysgenesis)
+	Sterile      bool  // a sterile fly never mates (e.g. gonadal atrophy of dysgenic flies); the fitness is not affected
+	Node1        int64 // node of Hap1 in the genealogy (tree sequence)
+	Node2        int64 // node of Hap2 in the genealogy (tree sequence)
+
+	Origins1 []InsertionOrigin // origin of each insertion of Hap1 (same order); only with insertion tracking
+	Origins2 []InsertionOrigin // origin of each insertion of Hap2 (same order); only with insertion tracking
+}
+
+/*
+The piRNA state a mother passes on to her offspring
+*/
+type MaternalState struct {
+	Origin      int64   // number of the fly that triggered the maternal piRNAs; 0 = no maternal piRNAs
+	Paramutated bool    // the mother has paramutated loci
+	Level       float64 // piRNA level of the mother
 }
+
+func (f *Fly) getMaternalState() MaternalState {
+	return MaternalState{Origin: f.Matpirna, Paramutated: f.Paramutated, Level: f.Pirna}
+}
+
 type FlyStatistic struct {
 	CountTotal     int64
 	CountCluster   int64
@@ -52,26 +77,37 @@ Second the number of new insertions will be computed based on
 i) the TE insertions in the diploid parent
 ii) piRNA cluster insertions
 iii) maternal piRNAs and paramutable sites
-iv) the transposition rate.
+iv) the transposition rate; which may be elevated in dysgenic flies.
 The number and position of new insertions will be random.
 Multiple insertions at the same site will be ignored.
 */
 func (f *Fly) GetGamete() []int64 {
+	gamete, _, _ := f.getGamete()
+	return gamete
+}
+
+/*
+Get a gamete from the Fly (see GetGamete);
+also returns the recombination events and the novel insertions (not present in the recombined gamete) which are needed for recording the genealogy
+*/
+func (f *Fly) getGamete() ([]int64, []int64, []int64) {
 	if f.FlyStat == nil {
 		panic("Fly statistics not initialized")
 	}
 	// First get recombined game
-	gamete := f.getRecombinedGamete()
+	gamete, recsites := f.getRecombinedGamete()
 
 	// Second introduce novel transposition events
 	counttotal := int64(len(f.Hap1) + len(f.Hap2))
 
 	// the function generates novel transposition events for a HAPLOID genome, i.e. a gamete
 	// if f.matpirna > 0 than we have piRNAs and thus no novel insertions (zero is default)
-	newsites := env.GetNewTranspositionSites(counttotal, f.Matpirna > 0)
+	// with quantitative piRNA levels the transposition rate is interpolated between u and uc
+	newsites := env.GetNewTranspositionSites(counttotal, pilevel.getSilencing(f), dysgen.getTranspositionFactor(f))
+	novel := getNovelMutations(gamete, newsites)
 
 	// merge old and new insertion sites, make them unique and sort
-	return util.MergeUniqueSort(gamete, newsites)
+	return util.MergeUniqueSort(gamete, newsites), recsites, novel
 
 }
 
@@ -92,29 +128,40 @@ func getFlyStat(femgam []int64, malegam []int64) FlyStatistic {
 	return fs
 }
 
-func getMaternalPirnaStatus(fstat FlyStatistic, matpirna int64, fc int64) int64 {
+/*
+Determine the piRNA status of a fly, given its insertions and the piRNA status of the mother;
+returns the origin of the piRNAs (number of the fly that triggered them; 0 = no piRNAs) and whether the piRNAs are produced by paramutated loci.
+Gain: a cluster insertion or trigger + paramutable insertions (probability pTrigger).
+Preserve: a cluster insertion or paramutable insertions that are paramutated by the maternal piRNAs;
+paramutation is established (pEstablish) when the mother had no paramutated loci, maintained (pMaintain) otherwise, and may be lost spontaneously (pLoss)
+*/
+func getMaternalPirnaStatus(fstat FlyStatistic, matpirna int64, matpara bool, fc int64) (int64, bool) {
 	// Gain maternal piRNAs
 	if matpirna == 0 {
 		// check for new trigger events
-
-		if fstat.CountCluster > 0 {
-			return fc // if there are cluster insertions -> we gained maternal piRNAs
-		} else if fstat.CountTrigger > 0 && fstat.CountPara > 0 {
-			return fc // if there are paramutable sites and trigger sites -> we gained maternal piRNAs
+		triggered := fstat.CountTrigger > 0 && fstat.CountPara > 0 && util.Bernoulli(paramut.pTrigger)
+		if fstat.CountCluster > 0 || triggered {
+			return fc, triggered // if there are cluster insertions or paramutable sites and trigger sites -> we gained maternal piRNAs
 		} else {
-			return 0 // ok still no maternal piRNAs
+			return 0, false // ok still no maternal piRNAs
 		}
 	} else {
 		// ok there were maternal piRNAs: wuhu
-		// if there is a cluster insertion or a paramutable site -> maternal piRNAs are preserved
+		// if there is a cluster insertion or a paramutated site -> maternal piRNAs are preserved
 		// otherwise maternal piRNAs are LOST!
+		paramutated := false
+		if fstat.CountPara > 0 {
+			pParamutation := paramut.pEstablish
+			if matpara {
+				pParamutation = paramut.pMaintain
+			}
+			paramutated = util.Bernoulli(pParamutation) && !util.Bernoulli(paramut.pLoss)
+		}
 
-		if fstat.CountCluster > 0 {
-			return matpirna // cluster insertion -> preserve maternal piRNAs
-		} else if fstat.CountPara > 0 {
-			return matpirna // paramutable site -> preserve maternal piRNAs
+		if fstat.CountCluster > 0 || paramutated {
+			return matpirna, paramutated // cluster insertion or paramutated site -> preserve maternal piRNAs
 		} else {
-			return 0 // LOSS of maternal PIRNAS
+			return 0, false // LOSS of maternal PIRNAS
 		}
 	}
 }
@@ -171,14 +218,14 @@ func (f *Fly) recombine(recombinationEvents []int64) []int64 {
 }
 
 /*
-Get a recombined gamete for the two haplotypes of a fly.
+Get a recombined gamete for the two haplotypes of a fly, and the recombination events.
 Recombination events are random, according to environment settings (i.e. chromosomes, rec.rate)
 */
-func (f *Fly) getRecombinedGamete() []int64 {
+func (f *Fly) getRecombinedGamete() ([]int64, []int64) {
 
 	recsites := env.GetRecombinationEvents()
 	rec := f.recombine(recsites)
-	return rec
+	return rec, recsites
 }
 
 /*
@@ -210,6 +257,17 @@ Setup a new Fly; given the gametes, the sex, and the maternal piRNAs;
 Will i) merge gametes ii) compute stats iii) determine piRNA status iv) compute fitness v) increase FLYCOUNTER
 */
 func NewFly(femgam []int64, malegam []int64, sex Sex, matpirna int64) *Fly {
+	ms := MaternalState{Origin: matpirna}
+	if matpirna > 0 {
+		ms.Level = pilevel.saturation // flies of the base population with maternal piRNAs are fully silenced
+	}
+	return newFlyWithMaternalState(femgam, malegam, sex, ms)
+}
+
+/*
+Setup a new Fly; as NewFly but considering the entire piRNA state of the mother (paramutation, piRNA level)
+*/
+func newFlyWithMaternalState(femgam []int64, malegam []int64, sex Sex, ms MaternalState) *Fly {
 	// should give random numbers 0 or 1, ie male female
 	fstat := getFlyStat(femgam, malegam)
 	// multithreading lock and unlock
@@ -218,8 +276,12 @@ func NewFly(femgam []int64, malegam []int64, sex Sex, matpirna int64) *Fly {
 	FLYCOUNTER++
 	//flylock.Unlock()
 
-	matpi := getMaternalPirnaStatus(fstat, matpirna, currentCounter)
-	newFly := Fly{Hap1: malegam, Hap2: femgam, FlyNumber: currentCounter, Matpirna: matpi, Sex: Sex(sex), FlyStat: &fstat}
+	ps := pimodel.GetPirnaState(fstat, ms, currentCounter)
+	zygotic := ms.Origin == 0 && ps.Origin > 0 // piRNAs without maternal piRNAs, e.g. from paternally inherited cluster insertions
+	zyglevel := pilevel.getZygoticLevel(fstat, ps.Paramutated)
+	level := pilevel.getDepositedLevel(ms.Level) + zyglevel
+	newFly := Fly{Hap1: malegam, Hap2: femgam, FlyNumber: currentCounter, Matpirna: ps.Origin, Paramutated: ps.Paramutated, Zygotic: zygotic,
+		Pirna: level, PirnaZygotic: zyglevel, Sex: Sex(sex), FlyStat: &fstat}
 	newFly.Fitness = GetFitness(&newFly)
 
 	return &newFly
@@ -148,6 +148,7 @@ func TestGenerateCumFitness(test *testing.T) {
 		{flies: []Fly{Fly{Fitness: 4.0}, Fly{Fitness: 2.0}, Fly{Fitness: 1.0}, Fly{Fitness: 3.0}}, want: []float64{0.4, 0.7, 0.9, 1.0}},
 		{flies: []Fly{Fly{Fitness: 0.4}, Fly{Fitness: 0.2}, Fly{Fitness: 0.1}, Fly{Fitness: 0.3}}, want: []float64{0.4, 0.7, 0.9, 1.0}},
 		{flies: []Fly{Fly{Fitness: 0.04}, Fly{Fitness: 0.02}, Fly{Fitness: 0.01}, Fly{Fitness: 0.03}}, want: []float64{0.4, 0.7, 0.9, 1.0}},
+		{flies: []Fly{Fly{Fitness: 1.0, Sterile: true}, Fly{Fitness: 1.0}}, want: []float64{1.0, 1.0}},
 	}
 	for _, t := range tests {
 		cf := generateCumFitness(t.flies)
@@ -246,7 +247,7 @@ func TestGetMaternalPirnaStatus(test *testing.T) {
 	}
 
 	for _, t := range tests {
-		got := getMaternalPirnaStatus(t.fs, t.matpi, t.fc)
+		got, _ := getMaternalPirnaStatus(t.fs, t.matpi, false, t.fc)
 
 		if got != t.want {
 			test.Errorf("Incorrect getMaternalPirnaStatus(); got %d, want %d", got, t.want)
@@ -306,3 +307,156 @@ func TestStochasticGetMatePairs(test *testing.T) {
 		}
 	}
 }
+
+func TestIsDysgenicCross(test *testing.T) {
+	var tests = []struct {
+		femmatpi int64
+		maletot  int64
+		want     bool
+	}{
+		{femmatpi: 0, maletot: 0, want: false},
+		{femmatpi: 0, maletot: 1, want: true},   // naive mother, father with TEs
+		{femmatpi: 12, maletot: 1, want: false}, // mother with piRNAs
+		{femmatpi: 12, maletot: 0, want: false},
+	}
+	for _, t := range tests {
+		female := Fly{Matpirna: t.femmatpi, FlyStat: &FlyStatistic{}}
+		male := Fly{FlyStat: &FlyStatistic{CountTotal: t.maletot}}
+		got := isDysgenicCross(&female, &male)
+		if got != t.want {
+			test.Errorf("Incorrect isDysgenicCross(); got %t, want %t", got, t.want)
+		}
+	}
+}
+
+func TestMakeDysgenic(test *testing.T) {
+	var tests = []struct {
+		dys        Dysgenesis
+		wantweight float64
+		wantfactor float64
+	}{
+		{dys: Dysgenesis{uFactor: 1.0, sterility: 0.0}, wantweight: 1.0, wantfactor: 1.0},
+		{dys: Dysgenesis{uFactor: 5.0, sterility: 0.0}, wantweight: 1.0, wantfactor: 5.0},
+		{dys: Dysgenesis{uFactor: 5.0, sterility: 1.0}, wantweight: 0.0, wantfactor: 5.0},
+	}
+	for _, t := range tests {
+		f := Fly{Fitness: 1.0}
+		if t.dys.getTranspositionFactor(&f) != 1.0 {
+			test.Errorf("Incorrect transposition factor for non-dysgenic fly")
+		}
+		t.dys.makeDysgenic(&f)
+		if !f.Dysgenic {
+			test.Errorf("Fly should be dysgenic")
+		}
+		if f.Fitness != 1.0 {
+			test.Errorf("Sterility must not change the fitness; got %f", f.Fitness)
+		}
+		if got := f.getMatingWeight(); got != t.wantweight {
+			test.Errorf("Incorrect mating weight of dysgenic fly; got %f, want %f", got, t.wantweight)
+		}
+		if got := t.dys.getTranspositionFactor(&f); got != t.wantfactor {
+			test.Errorf("Incorrect transposition factor of dysgenic fly; got %f, want %f", got, t.wantfactor)
+		}
+	}
+}
+
+func TestGetMaternalPirnaStatusParamutation(test *testing.T) {
+	var tests = []struct {
+		pm       Paramutation
+		fs       FlyStatistic
+		matpi    int64
+		matpara  bool
+		want     int64
+		wantpara bool
+	}{
+		{pm: Paramutation{pTrigger: 0.0, pEstablish: 1.0, pMaintain: 1.0}, fs: FlyStatistic{CountPara: 1, CountTrigger: 1}, matpi: 0, want: 0},                   // no trigger
+		{pm: Paramutation{pTrigger: 1.0, pEstablish: 1.0, pMaintain: 1.0}, fs: FlyStatistic{CountPara: 1, CountTrigger: 1}, matpi: 0, want: 133, wantpara: true}, // trigger
+		{pm: Paramutation{pTrigger: 0.0, pEstablish: 1.0, pMaintain: 1.0}, fs: FlyStatistic{CountCluster: 1, CountPara: 1, CountTrigger: 1}, matpi: 0, want: 133},
+		{pm: Paramutation{pTrigger: 1.0, pEstablish: 0.0, pMaintain: 1.0}, fs: FlyStatistic{CountPara: 1}, matpi: 211, matpara: false, want: 0},                   // no establishment
+		{pm: Paramutation{pTrigger: 1.0, pEstablish: 0.0, pMaintain: 1.0}, fs: FlyStatistic{CountPara: 1}, matpi: 211, matpara: true, want: 211, wantpara: true},  // maintenance
+		{pm: Paramutation{pTrigger: 1.0, pEstablish: 1.0, pMaintain: 0.0}, fs: FlyStatistic{CountPara: 1}, matpi: 211, matpara: false, want: 211, wantpara: true}, // establishment
+		{pm: Paramutation{pTrigger: 1.0, pEstablish: 1.0, pMaintain: 0.0}, fs: FlyStatistic{CountPara: 1}, matpi: 211, matpara: true, want: 0},                    // no maintenance
+		{pm: Paramutation{pTrigger: 1.0, pEstablish: 1.0, pMaintain: 1.0, pLoss: 1.0}, fs: FlyStatistic{CountPara: 1}, matpi: 211, matpara: true, want: 0},        // loss
+		{pm: Paramutation{pTrigger: 1.0, pEstablish: 1.0, pMaintain: 1.0, pLoss: 1.0}, fs: FlyStatistic{CountCluster: 1, CountPara: 1}, matpi: 211, want: 211},    // cluster preserves piRNAs
+	}
+	defer func(old Paramutation) { paramut = old }(paramut)
+	for _, t := range tests {
+		paramut = t.pm
+		got, gotpara := getMaternalPirnaStatus(t.fs, t.matpi, t.matpara, 133)
+		if got != t.want || gotpara != t.wantpara {
+			test.Errorf("Incorrect getMaternalPirnaStatus(); got %d %t, want %d %t", got, gotpara, t.want, t.wantpara)
+		}
+	}
+}
+
+func TestPirnaLevels(test *testing.T) {
+	pl := PirnaLevels{active: true, deposition: 0.5, production: 2.0, saturation: 4.0}
+	var tests = []struct {
+		fs            FlyStatistic
+		paramutated   bool
+		matlevel      float64
+		want          float64
+		wantsilencing float64
+	}{
+		{fs: FlyStatistic{}, matlevel: 0.0, want: 0.0, wantsilencing: 0.0},
+		{fs: FlyStatistic{}, matlevel: 4.0, want: 2.0, wantsilencing: 0.5}, // decay of maternal piRNAs
+		{fs: FlyStatistic{CountCluster: 1}, matlevel: 0.0, want: 2.0, wantsilencing: 0.5},
+		{fs: FlyStatistic{CountCluster: 1, CountPara: 2}, matlevel: 0.0, want: 2.0, wantsilencing: 0.5},
+		{fs: FlyStatistic{CountCluster: 1, CountPara: 2}, paramutated: true, matlevel: 0.0, want: 6.0, wantsilencing: 1.0},
+		{fs: FlyStatistic{CountCluster: 1}, matlevel: 4.0, want: 4.0, wantsilencing: 1.0},
+	}
+	for _, t := range tests {
+		zyg := pl.getZygoticLevel(t.fs, t.paramutated)
+		got := pl.getDepositedLevel(t.matlevel) + zyg
+		if math.Abs(got-t.want) > 0.0001 {
+			test.Errorf("Incorrect piRNA level; got %f, want %f", got, t.want)
+		}
+		f := Fly{Pirna: got, PirnaZygotic: zyg}
+		if gots := pl.getSilencing(&f); math.Abs(gots-t.wantsilencing) > 0.0001 {
+			test.Errorf("Incorrect getSilencing(); got %f, want %f", gots, t.wantsilencing)
+		}
+	}
+}
+
+func TestZygoticSilencing(test *testing.T) {
+	var tests = []struct {
+		pl   PirnaLevels
+		f    Fly
+		want float64
+	}{
+		{pl: PirnaLevels{active: false, zygoticEfficacy: 1.0}, f: Fly{Matpirna: 5, Zygotic: true}, want: 1.0},
+		{pl: PirnaLevels{active: false, zygoticEfficacy: 0.0}, f: Fly{Matpirna: 5, Zygotic: true}, want: 0.0},  // delay
+		{pl: PirnaLevels{active: false, zygoticEfficacy: 0.0}, f: Fly{Matpirna: 5, Zygotic: false}, want: 1.0}, // maternal piRNAs
+		{pl: PirnaLevels{active: false, zygoticEfficacy: 0.3}, f: Fly{Matpirna: 5, Zygotic: true}, want: 0.3},
+		{pl: PirnaLevels{active: true, saturation: 2.0, zygoticEfficacy: 0.5}, f: Fly{Matpirna: 5, Zygotic: true, Pirna: 2.0, PirnaZygotic: 2.0}, want: 0.5},
+		{pl: PirnaLevels{active: true, saturation: 2.0, zygoticEfficacy: 0.5}, f: Fly{Matpirna: 5, Zygotic: false, Pirna: 2.0, PirnaZygotic: 2.0}, want: 1.0},
+		{pl: PirnaLevels{active: true, saturation: 4.0, zygoticEfficacy: 0.0}, f: Fly{Matpirna: 5, Zygotic: true, Pirna: 3.0, PirnaZygotic: 2.0}, want: 0.25},
+	}
+	for _, t := range tests {
+		got := t.pl.getSilencing(&t.f)
+		if math.Abs(got-t.want) > 0.0001 {
+			test.Errorf("Incorrect getSilencing(); got %f, want %f", got, t.want)
+		}
+	}
+}
+
+func TestTrapModel(test *testing.T) {
+	SetupPirnaModel("Trap")
+	var tests = []struct {
+		fs   FlyStatistic
+		ms   MaternalState
+		want PirnaState
+	}{
+		{fs: FlyStatistic{}, ms: MaternalState{}, want: PirnaState{}},
+		{fs: FlyStatistic{CountCluster: 1}, ms: MaternalState{}, want: PirnaState{Origin: 133}},
+		{fs: FlyStatistic{CountPara: 1, CountTrigger: 1}, ms: MaternalState{}, want: PirnaState{Origin: 133, Paramutated: true}},
+		{fs: FlyStatistic{CountPara: 1}, ms: MaternalState{Origin: 211}, want: PirnaState{Origin: 211, Paramutated: true}},
+		{fs: FlyStatistic{}, ms: MaternalState{Origin: 211, Paramutated: true}, want: PirnaState{}},
+	}
+	for _, t := range tests {
+		got := pimodel.GetPirnaState(t.fs, t.ms, 133)
+		if got != t.want {
+			test.Errorf("Incorrect TrapModel.GetPirnaState(); got %v, want %v", got, t.want)
+		}
+	}
+}
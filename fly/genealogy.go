@@ -0,0 +1,79 @@
+package fly
+
+import (
+	"invade/env"
+	"invade/treeseq"
+)
+
+/*
+Start recording the genealogy of a base population; each haplotype is a node born in generation 0 and each insertion is a mutation
+*/
+func (p *Population) initializeGenealogy() {
+	if !treeseq.IsActive() {
+		return
+	}
+	treeseq.Reset(env.GetGenomeSize())
+	for i := range p.Flies {
+		f := &p.Flies[i]
+		f.Node1 = treeseq.AddNode(p.generation)
+		f.Node2 = treeseq.AddNode(p.generation)
+		treeseq.AddMutations(f.Node1, f.Hap1)
+		treeseq.AddMutations(f.Node2, f.Hap2)
+	}
+}
+
+/*
+Record the genealogy of a novel fly;
+Hap1 is the paternal gamete and Hap2 the maternal gamete (see NewFly), both gametes start with the first haplotype of the parent (see recombine)
+*/
+func recordOffspring(f *Fly, mp matePair, generation int64, femrec []int64, femnovel []int64, malrec []int64, malnovel []int64) {
+	if !treeseq.IsActive() {
+		return
+	}
+	f.Node1 = treeseq.AddNode(generation)
+	f.Node2 = treeseq.AddNode(generation)
+	treeseq.AddGamete(mp.male.Node1, mp.male.Node2, f.Node1, malrec)
+	treeseq.AddGamete(mp.female.Node1, mp.female.Node2, f.Node2, femrec)
+	treeseq.AddMutations(f.Node1, malnovel)
+	treeseq.AddMutations(f.Node2, femnovel)
+}
+
+/*
+Simplify the genealogy with respect to the haplotypes of the population; the nodes of the flies are updated
+*/
+func (p *Population) SimplifyGenealogy() {
+	if !treeseq.IsActive() {
+		return
+	}
+	samples := make([]int64, 0, 2*len(p.Flies))
+	for _, f := range p.Flies {
+		samples = append(samples, f.Node1, f.Node2)
+	}
+	newids := treeseq.Simplify(samples)
+	for i := range p.Flies {
+		p.Flies[i].Node1 = newids[2*i]
+		p.Flies[i].Node2 = newids[2*i+1]
+	}
+}
+
+/*
+Get the novel insertions of a gamete, i.e. new insertion sites that are not yet present in the recombined gamete;
+solely needed for recording the genealogy
+*/
+func getNovelMutations(recombined []int64, newsites []int64) []int64 {
+	if !treeseq.IsActive() || len(newsites) == 0 {
+		return nil
+	}
+	present := make(map[int64]bool)
+	for _, s := range recombined {
+		present[s] = true
+	}
+	novel := make([]int64, 0, len(newsites))
+	for _, s := range newsites {
+		if !present[s] {
+			novel = append(novel, s)
+			present[s] = true
+		}
+	}
+	return novel
+}
@@ -0,0 +1,171 @@
+package fly
+
+import (
+	"sort"
+)
+
+/*
+Origin of a TE insertion; the generation in which the insertion arose and the fly that produced the gamete carrying the novel insertion.
+Insertions in the base population have generation 0 and fly 0
+*/
+type InsertionOrigin struct {
+	Generation int64
+	Fly        int64
+}
+
+/*
+Keeps track of the origin of each insertion.
+The origins are stored with the insertions of a fly (Origins1 and Origins2, in the order of Hap1 and Hap2), i.e. each copy of an insertion has its own origin:
+a gamete inherits the origins of the recombined insertions (getGameteOrigins) and novel insertions get the current generation and the fly producing the gamete.
+Thus a novel insertion at a site that is already segregating in the population has a novel origin, unless the gamete already carries an insertion at the site
+*/
+type InsertionTracker struct {
+	active     bool
+	generation int64
+}
+
+var tracker = InsertionTracker{active: false}
+
+func SetupInsertionTracking(active bool) {
+	tracker = InsertionTracker{active: active}
+}
+
+/*
+Reset the tracker for a base population (generation 0); all insertions of the base population have generation 0 and fly 0
+*/
+func (t *InsertionTracker) reset(p *Population) {
+	if !t.active {
+		return
+	}
+	t.generation = 0
+	for i := range p.Flies {
+		p.Flies[i].Origins1 = make([]InsertionOrigin, len(p.Flies[i].Hap1))
+		p.Flies[i].Origins2 = make([]InsertionOrigin, len(p.Flies[i].Hap2))
+	}
+}
+
+/*
+Get the origins of the insertions of a gamete, given the recombination events (see recombine);
+recombination starts with Hap1 and switches the haplotype at each event, thus a site is inherited from Hap2 if an odd number of events precede the site.
+Sites that are not present in the haplotype are novel insertions of the current generation; nil if the tracker is not active
+*/
+func (t *InsertionTracker) getGameteOrigins(f *Fly, gamete []int64, recsites []int64) []InsertionOrigin {
+	if !t.active {
+		return nil
+	}
+	origins := make([]InsertionOrigin, len(gamete))
+	irec, ihap1, ihap2 := 0, 0, 0
+	for i, s := range gamete {
+		for irec < len(recsites) && recsites[irec] <= s {
+			irec++
+		}
+		hap, hapori, ihap := f.Hap1, f.Origins1, &ihap1
+		if irec%2 == 1 {
+			hap, hapori, ihap = f.Hap2, f.Origins2, &ihap2
+		}
+		for *ihap < len(hap) && hap[*ihap] < s {
+			*ihap++
+		}
+		if *ihap < len(hap) && hap[*ihap] == s {
+			origins[i] = hapori[*ihap]
+		} else {
+			origins[i] = InsertionOrigin{Generation: t.generation, Fly: f.FlyNumber}
+		}
+	}
+	return origins
+}
+
+/*
+Get the origin of each site in the population; for sites with several origins (recurrent insertions) the oldest origin;
+empty if the insertions are not tracked
+*/
+func (p *Population) GetInsertionOrigins() map[int64]InsertionOrigin {
+	toret := make(map[int64]InsertionOrigin)
+	add := func(sites []int64, origins []InsertionOrigin) {
+		if len(origins) != len(sites) {
+			return
+		}
+		for i, s := range sites {
+			if o, ok := toret[s]; !ok || isOlderOrigin(origins[i], o) {
+				toret[s] = origins[i]
+			}
+		}
+	}
+	for _, f := range p.Flies {
+		add(f.Hap1, f.Origins1)
+		add(f.Hap2, f.Origins2)
+	}
+	return toret
+}
+
+func isOlderOrigin(o InsertionOrigin, than InsertionOrigin) bool {
+	return o.Generation < than.Generation || (o.Generation == than.Generation && o.Fly < than.Fly)
+}
+
+func IsInsertionTrackingActive() bool {
+	return tracker.active
+}
+
+/*
+Age spectrum of the insertions in a population, i.e. the number of sites and the number of copies (haploid genomes with the insertion) for each age
+*/
+type AgeClass struct {
+	Age    int64
+	Sites  int64
+	Copies int64
+}
+
+/*
+Get the age spectrum of all insertions in the population, i.e. of each copy; sorted by age.
+A site with copies of different ages (recurrent insertions) contributes to each of the ages
+*/
+func (p *Population) GetAgeSpectrum() []AgeClass {
+	type siteAge struct {
+		site int64
+		age  int64
+	}
+	copies := make(map[siteAge]int64)
+	for _, f := range p.Flies {
+		for i, s := range f.Hap1 {
+			copies[siteAge{site: s, age: p.getAge(f.Origins1, i)}]++
+		}
+		for i, s := range f.Hap2 {
+			copies[siteAge{site: s, age: p.getAge(f.Origins2, i)}]++
+		}
+	}
+	classes := make(map[int64]*AgeClass)
+	for sa, c := range copies {
+		if _, ok := classes[sa.age]; !ok {
+			classes[sa.age] = &AgeClass{Age: sa.age}
+		}
+		classes[sa.age].Sites++
+		classes[sa.age].Copies += c
+	}
+	toret := make([]AgeClass, 0, len(classes))
+	for _, ac := range classes {
+		toret = append(toret, *ac)
+	}
+	sort.Slice(toret, func(i, j int) bool { return toret[i].Age < toret[j].Age })
+	return toret
+}
+
+/*
+The age of the i-th insertion of a haplotype in generations; -1 if the insertion is not tracked
+*/
+func (p *Population) getAge(origins []InsertionOrigin, i int) int64 {
+	if i >= len(origins) {
+		return -1
+	}
+	return p.GetInsertionAge(origins[i])
+}
+
+/*
+Get the age of an insertion with the given origin in generations
+*/
+func (p *Population) GetInsertionAge(o InsertionOrigin) int64 {
+	return p.generation - o.Generation
+}
+
+func (p *Population) GetGeneration() int64 {
+	return p.generation
+}
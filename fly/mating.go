@@ -39,6 +39,16 @@ func getMatePairs(flies []Fly, n int64) []matePair {
 	return merryCouples
 }
 
+/*
+The weight of a fly in the mate choice; the fitness, or zero for sterile flies
+*/
+func (f *Fly) getMatingWeight() float64 {
+	if f.Sterile {
+		return 0.0
+	}
+	return f.Fitness
+}
+
 func generateCumFitness(flies []Fly) []cumFitFly {
 	// Here major go confusion arose with pointers I guess
 	// Video
@@ -48,7 +58,7 @@ func generateCumFitness(flies []Fly) []cumFitFly {
 	// so that all flies in cumFitFly were pointing to the same fly in the end!!
 	// This is a very strange behaviour -> major shit feature of GO
 	// it may speed up things if sorted; largest fitness first; and cum sum between zero and 1
-	sort.Slice(flies, func(i, j int) bool { return flies[i].Fitness > flies[j].Fitness })
+	sort.Slice(flies, func(i, j int) bool { return flies[i].getMatingWeight() > flies[j].getMatingWeight() })
 
 	// now get the sum of all fitnesses
 	var fitsum float64 = 0.0
@@ -56,7 +66,7 @@ func generateCumFitness(flies []Fly) []cumFitFly {
 		if f.Fitness < 0.0 {
 			panic("Fitness must be larger than zero")
 		}
-		fitsum += f.Fitness
+		fitsum += f.getMatingWeight()
 	}
 	// generate the cumFitFlies
 
@@ -68,7 +78,7 @@ func generateCumFitness(flies []Fly) []cumFitFly {
 		// I Could not use &f as this was always referring to the same address
 		// BE super careful with range and pointers
 		//print(fi)
-		w := f.Fitness / fitsum // fitness scaled by the total fitness such that the Sum of all is 1.0
+		w := f.getMatingWeight() / fitsum // fitness scaled by the total fitness such that the Sum of all is 1.0
 		runningsum += w
 
 		c := cumFitFly{fly: fi, cumFit: runningsum}
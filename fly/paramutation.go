@@ -0,0 +1,27 @@
+package fly
+
+import (
+	"fmt"
+)
+
+/*
+Probabilities for the gain, maintenance and loss of piRNAs per transmission (i.e. per generation);
+With the defaults (1.0, 1.0, 1.0, 0.0) the piRNA status of a fly is fully deterministic
+*/
+type Paramutation struct {
+	pTrigger   float64 // probability that trigger + paramutable insertions start the production of piRNAs
+	pEstablish float64 // probability that maternal piRNAs paramutate an insertion in a paramutable locus (mother without paramutated loci)
+	pMaintain  float64 // probability that paramutation is maintained (mother with paramutated loci)
+	pLoss      float64 // probability of a spontaneous loss of paramutation
+}
+
+var paramut = Paramutation{pTrigger: 1.0, pEstablish: 1.0, pMaintain: 1.0, pLoss: 0.0}
+
+func SetupParamutation(pTrigger float64, pEstablish float64, pMaintain float64, pLoss float64) {
+	for _, p := range []float64{pTrigger, pEstablish, pMaintain, pLoss} {
+		if p < 0.0 || p > 1.0 {
+			panic(fmt.Sprintf("invalid probability for paramutation %f; must be between 0.0 and 1.0", p))
+		}
+	}
+	paramut = Paramutation{pTrigger: pTrigger, pEstablish: pEstablish, pMaintain: pMaintain, pLoss: pLoss}
+}
@@ -0,0 +1,89 @@
+package fly
+
+/*
+Quantitative piRNA levels;
+the piRNA level of a fly is the sum of the maternally deposited piRNAs (a fraction of the level of the mother; decays over generations without production)
+and the zygotically produced piRNAs (proportional to the number of cluster insertions and, if paramutated, paramutable insertions).
+If inactive, the piRNA status is binary, i.e. a fly with maternal piRNAs is fully silenced (uc) and a fly without piRNAs is not (u).
+In both cases piRNAs that are solely produced zygotically (i.e. the mother had no piRNAs) silence with a reduced efficacy
+*/
+type PirnaLevels struct {
+	active          bool
+	deposition      float64 // fraction of the maternal piRNA level deposited in the offspring
+	production      float64 // piRNA level produced by each cluster insertion (or paramutated insertion)
+	saturation      float64 // piRNA level at which TEs are fully silenced
+	zygoticEfficacy float64 // efficacy of zygotic piRNAs in flies without maternal piRNAs; 0.0 = silencing is delayed to the next generation
+}
+
+var pilevel = PirnaLevels{active: false, deposition: 0.0, production: 1.0, saturation: 1.0, zygoticEfficacy: 1.0}
+
+func SetupPirnaLevels(active bool, deposition float64, production float64, saturation float64) {
+	if deposition < 0.0 || deposition > 1.0 {
+		panic("invalid maternal deposition of piRNAs; must be between 0.0 and 1.0")
+	}
+	if production < 0.0 {
+		panic("invalid production of piRNAs; must be larger or equal to 0.0")
+	}
+	if saturation <= 0.0 {
+		panic("invalid saturation level of piRNAs; must be larger than 0.0")
+	}
+	pilevel = PirnaLevels{active: active, deposition: deposition, production: production, saturation: saturation, zygoticEfficacy: pilevel.zygoticEfficacy}
+}
+
+/*
+Efficacy of zygotically produced piRNAs in flies without maternal piRNAs (e.g. paternally inherited cluster insertions);
+1.0 = as effective as maternal piRNAs; 0.0 = no silencing in the fly itself, only in its offspring (delay)
+*/
+func SetupZygoticSilencing(efficacy float64) {
+	if efficacy < 0.0 || efficacy > 1.0 {
+		panic("invalid efficacy of zygotic piRNAs; must be between 0.0 and 1.0")
+	}
+	pilevel.zygoticEfficacy = efficacy
+}
+
+/*
+Is the quantitative piRNA model used?
+*/
+func IsPirnaLevelActive() bool {
+	return pilevel.active
+}
+
+/*
+Get the piRNA level deposited by the mother
+*/
+func (pl *PirnaLevels) getDepositedLevel(matlevel float64) float64 {
+	return pl.deposition * matlevel
+}
+
+/*
+Get the piRNA level produced zygotically, by cluster insertions and paramutated insertions
+*/
+func (pl *PirnaLevels) getZygoticLevel(fstat FlyStatistic, paramutated bool) float64 {
+	zygotic := float64(fstat.CountCluster) * pl.production
+	if paramutated {
+		zygotic += float64(fstat.CountPara) * pl.production
+	}
+	return zygotic
+}
+
+/*
+Get the level of silencing of a fly, between 0.0 (no silencing; transposition rate u) and 1.0 (full silencing; transposition rate uc)
+*/
+func (pl *PirnaLevels) getSilencing(f *Fly) float64 {
+	efficacy := 1.0
+	if f.Zygotic {
+		efficacy = pl.zygoticEfficacy
+	}
+	if !pl.active {
+		if f.Matpirna > 0 {
+			return efficacy
+		}
+		return 0.0
+	}
+	maternal := f.Pirna - f.PirnaZygotic
+	silencing := (maternal + efficacy*f.PirnaZygotic) / pl.saturation
+	if silencing > 1.0 {
+		silencing = 1.0
+	}
+	return silencing
+}
@@ -0,0 +1,71 @@
+package fly
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+The piRNA state of a fly, as determined by a piRNA model
+*/
+type PirnaState struct {
+	Origin      int64 // number of the fly that triggered the piRNAs; 0 = no piRNAs
+	Paramutated bool  // piRNAs are produced by paramutated loci
+}
+
+/*
+Rules for the biogenesis of piRNAs; i.e. gain, maintenance and loss of piRNAs, and the phases of an invasion.
+Alternative models (e.g. a cluster-independent siRNA model) may be added by implementing this interface and registering them in pirnaModels
+*/
+type PirnaModel interface {
+	// piRNA state of a new fly given its statistics, the maternal state and the number of the fly
+	GetPirnaState(fstat FlyStatistic, ms MaternalState, fc int64) PirnaState
+	// phase of the invasion in a new population given the phase of the previous population
+	UpdatePhase(newPop *Population, oldPhase Phase) Phase
+}
+
+/*
+The trap model; piRNAs are triggered by cluster insertions (or trigger + paramutable insertions),
+maintained by cluster insertions and paramutated insertions, and lost otherwise
+*/
+type TrapModel struct{}
+
+func (t TrapModel) GetPirnaState(fstat FlyStatistic, ms MaternalState, fc int64) PirnaState {
+	origin, para := getMaternalPirnaStatus(fstat, ms.Origin, ms.Paramutated, fc)
+	return PirnaState{Origin: origin, Paramutated: para}
+}
+
+func (t TrapModel) UpdatePhase(newPop *Population, oldPhase Phase) Phase {
+	return updatePhase(newPop, oldPhase)
+}
+
+var pimodel PirnaModel = TrapModel{}
+
+/*
+The available piRNA models; the name (lower case) and the constructor
+*/
+var pirnaModels = map[string]func() PirnaModel{
+	"trap": func() PirnaModel { return TrapModel{} },
+}
+
+/*
+The names of the available piRNA models, sorted
+*/
+func GetPirnaModelNames() []string {
+	names := []string{}
+	for name := range pirnaModels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func SetupPirnaModel(name string) {
+	name = strings.ToLower(name)
+	constructor, ok := pirnaModels[name]
+	if !ok {
+		panic(fmt.Sprintf("unknown piRNA model %s; must be one of %s", name, strings.Join(GetPirnaModelNames(), ", ")))
+	}
+	pimodel = constructor()
+}
@@ -2,12 +2,14 @@ package fly
 
 import (
 	"invade/env"
+	"invade/treeseq"
 )
 
 type Population struct {
-	Flies  []Fly
-	phase  Phase
-	minFit float64
+	Flies      []Fly
+	phase      Phase
+	minFit     float64
+	generation int64
 }
 
 type Phase int64
@@ -22,12 +24,14 @@ const (
 type PopStatus int64
 
 const (
-	BASEPOP PopStatus = 0
-	OK      PopStatus = 1
-	FAIL0   PopStatus = 2
-	FAILW   PopStatus = 3
-	FAILSEX PopStatus = 4
-	FAILMAX PopStatus = 5
+	BASEPOP  PopStatus = 0
+	OK       PopStatus = 1
+	FAIL0    PopStatus = 2
+	FAILW    PopStatus = 3
+	FAILSEX  PopStatus = 4
+	FAILMAX  PopStatus = 5
+	FAILSTER PopStatus = 6 // all females or all males are sterile
+	STOPPED  PopStatus = 7 // the simulation was stopped by a user-defined stop condition
 )
 
 func (p *Population) Size() int64 {
@@ -37,64 +41,51 @@ func (p *Population) Size() int64 {
 func InitializePopulation(flies []Fly) *Population {
 	p := Population{Flies: flies}
 	p.minFit = p.GetAverageFitness()
-	p.phase = updatePhase(&p, RAPIDINVASION)
+	p.phase = pimodel.UpdatePhase(&p, RAPIDINVASION)
+	tracker.reset(&p)
+	p.initializeGenealogy()
 	return &p
 }
 
 /*
 Get the next generation i) get mate pairs according to fitness ii) get gametes with random recombination and transposition iii) get random sex iv) generate new flies
-v) compute fitness and statistics
+v) compute fitness and statistics vi) hybrid dysgenesis
 */
 func (p *Population) GetNextGeneration() *Population {
+	tracker.generation = p.generation + 1
 	matePairs := getMatePairs(p.Flies, int64(len(p.Flies)))
 	nextGen := make([]Fly, len(matePairs))
 	for i, mp := range matePairs {
-		femgam := mp.female.GetGamete()
-		malgam := mp.male.GetGamete()
-		sex := GetRandomSex()
-		newFly := NewFly(femgam, malgam, sex, mp.female.Matpirna) // maternal piRNAs; only the female passes them
+		newFly := getOffspring(mp, p.generation+1)
 		nextGen[i] = *newFly
 	}
-	newPop := Population{Flies: nextGen}
-	newPhase := updatePhase(&newPop, p.phase)
+	newPop := Population{Flies: nextGen, generation: p.generation + 1}
+	newPhase := pimodel.UpdatePhase(&newPop, p.phase)
 	newPop.phase = newPhase
 	newMinFit := updateFitness(&newPop, p.minFit)
 	newPop.minFit = newMinFit
+	if treeseq.NeedsSimplification(newPop.generation) {
+		newPop.SimplifyGenealogy()
+	}
 	return &newPop
 }
 
-func getFly(mp matePair, fc chan<- *Fly) {
-	femgam := mp.female.GetGamete()
-	malgam := mp.male.GetGamete()
-	sex := GetRandomSex()
-	newFly := NewFly(femgam, malgam, sex, mp.female.Matpirna) // maternal piRNAs; only the female passes them
-	fc <- newFly
-}
-
 /*
-func (p *Population) GetNextGenerationMultithreading() *Population {
-	// Generate flies
-	flychan := make(chan *Fly)
-	matePairs := getMatePairs(p.Flies, int64(len(p.Flies)))
-	for i := 0; i < len(p.Flies); i++ {
-		go getFly(matePairs[i], flychan)
-	}
-
-	// collect the flies
-	nextGen := make([]Fly, len(matePairs))
-	for i := 0; i < len(p.Flies); i++ {
-		f := <-flychan
-		nextGen[i] = *f
-	}
-	if len(nextGen) != len(p.Flies) {
-		panic("multithreading fuckup")
+Get the offspring of a mate pair; i) gametes of both parents ii) random sex iii) maternal piRNAs iv) hybrid dysgenesis v) genealogy
+*/
+func getOffspring(mp matePair, generation int64) *Fly {
+	femgam, femrec, femnovel := mp.female.getGamete()
+	malgam, malrec, malnovel := mp.male.getGamete()
+	sex := GetRandomSex()
+	newFly := newFlyWithMaternalState(femgam, malgam, sex, mp.female.getMaternalState()) // maternal piRNAs; only the female passes them
+	newFly.Origins1 = tracker.getGameteOrigins(mp.male, malgam, malrec)                  // Hap1 is the paternal gamete
+	newFly.Origins2 = tracker.getGameteOrigins(mp.female, femgam, femrec)
+	if isDysgenicCross(mp.female, mp.male) {
+		dysgen.makeDysgenic(newFly)
 	}
-	newPop := Population{Flies: nextGen}
-	newPhase := updatePhase(&newPop, p.phase)
-	newPop.phase = newPhase
-	return &newPop
+	recordOffspring(newFly, mp, generation, femrec, femnovel, malrec, malnovel)
+	return newFly
 }
-*/
 
 /*
 Find the novel minimum Fitness;
@@ -110,7 +101,7 @@ func updateFitness(newPop *Population, previousMinFit float64) float64 {
 }
 
 /*
-update the phase of the invasion;
+update the phase of the invasion according to the trap model;
 RAPIDINVASION->TRIGGERED -> SHOTGUN -> INACTIVE
 */
 func updatePhase(newPop *Population, oldPhase Phase) Phase {
@@ -145,17 +136,25 @@ fail-0		no TEs left
 fail-w		fitness to low
 base 		base population
 fail-sex 	only males or only females
+fail-ster	all females or all males are sterile; no offspring
+stop		stopped by a user-defined stop condition (never returned by GetStatus)
 */
 func (p *Population) GetStatus() PopStatus {
 	fitcount := 0.0
 	femcount := 0
 	tecount := 0
+	fertilefem, fertilemale := 0, 0
 	for _, f := range p.Flies {
 		fitcount += f.Fitness
 		tecount += int(f.FlyStat.CountTotal)
 		if f.Sex == FEMALE {
 			femcount++
 		}
+		if !f.Sterile && f.Sex == FEMALE {
+			fertilefem++
+		} else if !f.Sterile {
+			fertilemale++
+		}
 	}
 	avfit := fitcount / float64(p.Size())
 	avins := p.GetAverageInsertions()
@@ -163,6 +162,8 @@ func (p *Population) GetStatus() PopStatus {
 		return FAIL0
 	} else if femcount == 0 || femcount == int(p.Size()) {
 		return FAILSEX
+	} else if fertilefem == 0 || fertilemale == 0 {
+		return FAILSTER
 	} else if avfit < env.GetMinimumFitness() {
 		return FAILW
 	} else if avins > env.GetMaximumInsertions() {
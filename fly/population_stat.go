@@ -50,6 +50,84 @@ func (p *Population) GetWithPirnaFrequency() float64 {
 	return p.Count2Freq(p.GetWithPirnaCount())
 }
 
+/*
+Frequency of the individuals with maternally deposited piRNAs
+*/
+func (p *Population) GetWithMaternalPirnaFrequency() float64 {
+	c := int64(0)
+	for _, f := range p.Flies {
+		if f.Matpirna > 0 && !f.Zygotic {
+			c++
+		}
+	}
+	return p.Count2Freq(c)
+}
+
+/*
+Frequency of the individuals with solely zygotically produced piRNAs, i.e. the mother had no piRNAs
+*/
+func (p *Population) GetWithZygoticPirnaFrequency() float64 {
+	c := int64(0)
+	for _, f := range p.Flies {
+		if f.Matpirna > 0 && f.Zygotic {
+			c++
+		}
+	}
+	return p.Count2Freq(c)
+}
+
+/*
+Get the average maternally deposited and zygotically produced piRNA level; returned in this order
+*/
+func (p *Population) GetAveragePirnaLevels() (float64, float64) {
+	mat := 0.0
+	zyg := 0.0
+	for _, f := range p.Flies {
+		mat += f.Pirna - f.PirnaZygotic
+		zyg += f.PirnaZygotic
+	}
+	n := float64(len(p.Flies))
+	return mat / n, zyg / n
+}
+
+/*
+Get the piRNA levels of all individuals; sorted in ascending order
+*/
+func (p *Population) GetPirnaLevels() []float64 {
+	levels := make([]float64, 0, len(p.Flies))
+	for _, f := range p.Flies {
+		levels = append(levels, f.Pirna)
+	}
+	sort.Float64s(levels)
+	return levels
+}
+
+/*
+Frequency of individuals in which TEs are fully silenced by piRNAs (transposition rate uc)
+*/
+func (p *Population) GetFullySilencedFrequency() float64 {
+	c := int64(0)
+	for i := range p.Flies {
+		if pilevel.getSilencing(&p.Flies[i]) >= 1.0 {
+			c++
+		}
+	}
+	return p.Count2Freq(c)
+}
+
+/*
+Frequency of dysgenic individuals, i.e. offspring of mothers without piRNAs and fathers with TEs
+*/
+func (p *Population) GetDysgenicFrequency() float64 {
+	c := int64(0)
+	for _, f := range p.Flies {
+		if f.Dysgenic {
+			c++
+		}
+	}
+	return p.Count2Freq(c)
+}
+
 /*
 	Get the fixed insertions in a population; the positions are provided
 */
@@ -293,48 +371,3 @@ type OriginFreq struct {
 	Origin int64
 	Freq   float64
 }
-
-func haplotypeContainsPosition(s []int64, p int64) bool {
-	for _, v := range s {
-		if v == p {
-			return true
-		}
-	}
-
-	return false
-}
-
-/*
-Compute linkage disequilibrium (D) between two loci. Use carefully, super slow.
-*/
-func (p *Population) GetD(locus1 int64, locus2 int64) float64 {
-	//https://en.wikipedia.org/wiki/Linkage_disequilibrium
-	x11 := 0
-	p1 := 0
-	p2 := 0
-	haps := p.GetHaplotypes()
-	hapcount := float64(len(haps))
-	for _, hap := range haps {
-		valid := true
-		if haplotypeContainsPosition(hap, locus1) {
-			p1++
-		} else {
-			valid = false
-		}
-		if haplotypeContainsPosition(hap, locus2) {
-			p2++
-		} else {
-			valid = false
-		}
-		if valid {
-			x11++
-		}
-
-	}
-	fx11 := float64(x11) / hapcount
-	fp1 := float64(p1) / hapcount
-	fp2 := float64(p2) / hapcount
-	d := fx11 - fp1*fp2
-	return d
-
-}
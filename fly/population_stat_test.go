@@ -2,6 +2,7 @@ package fly
 
 import (
 	"invade/env"
+	"invade/treeseq"
 	"math"
 	"testing"
 )
@@ -260,3 +261,116 @@ func TestGetHaplotypes(test *testing.T) {
 
 	}
 }
+
+func TestInsertionTracker(test *testing.T) {
+	testhelper_setdefaultenv()
+	SetupInsertionTracking(true)
+	defer SetupInsertionTracking(false)
+	pop := testhelper_hapmerger([][]int64{[]int64{10, 20}, []int64{10}})
+	tracker.reset(pop)
+	for _, f := range pop.Flies {
+		if len(f.Origins1) != len(f.Hap1) || len(f.Origins2) != len(f.Hap2) {
+			test.Errorf("Incorrect origins of the base population %v %v", f.Origins1, f.Origins2)
+		}
+	}
+
+	base, old, novel := InsertionOrigin{}, InsertionOrigin{Generation: 2, Fly: 5}, InsertionOrigin{Generation: 3, Fly: 17}
+	tracker.generation = 3
+	f := Fly{FlyNumber: 17, Hap1: []int64{10, 20}, Origins1: []InsertionOrigin{base, base}, Hap2: []int64{10, 30}, Origins2: []InsertionOrigin{old, old}}
+	var tests = []struct {
+		gamete []int64
+		rec    []int64
+		want   []InsertionOrigin
+	}{
+		{gamete: []int64{10, 20}, rec: []int64{}, want: []InsertionOrigin{base, base}},
+		{gamete: []int64{10, 30}, rec: []int64{5}, want: []InsertionOrigin{old, old}},
+		{gamete: []int64{10, 30}, rec: []int64{15}, want: []InsertionOrigin{base, old}},
+		{gamete: []int64{10, 20}, rec: []int64{5, 15}, want: []InsertionOrigin{old, base}},
+		{gamete: []int64{10, 20, 40}, rec: []int64{}, want: []InsertionOrigin{base, base, novel}},
+		{gamete: []int64{10, 20, 30}, rec: []int64{}, want: []InsertionOrigin{base, base, novel}}, // recurrent insertion at a segregating site
+	}
+	for _, t := range tests {
+		got := tracker.getGameteOrigins(&f, t.gamete, t.rec)
+		if len(got) != len(t.want) {
+			test.Errorf("Incorrect number of origins; got %v, want %v", got, t.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != t.want[i] {
+				test.Errorf("Incorrect origins of gamete %v; got %v, want %v", t.gamete, got, t.want)
+			}
+		}
+	}
+
+	pop = &Population{generation: 5, Flies: []Fly{Fly{Hap1: []int64{10, 30}, Origins1: []InsertionOrigin{base, novel}, Hap2: []int64{10, 30}, Origins2: []InsertionOrigin{old, old}}}}
+	origins := pop.GetInsertionOrigins()
+	if origins[10] != base || origins[30] != old {
+		test.Errorf("Incorrect origins of the sites %v", origins)
+	}
+	spec := pop.GetAgeSpectrum()
+	want := []AgeClass{{Age: 2, Sites: 1, Copies: 1}, {Age: 3, Sites: 2, Copies: 2}, {Age: 5, Sites: 1, Copies: 1}}
+	if len(spec) != len(want) {
+		test.Errorf("Incorrect age spectrum %v", spec)
+	} else {
+		for i := range want {
+			if spec[i] != want[i] {
+				test.Errorf("Incorrect age spectrum %v; want %v", spec, want)
+			}
+		}
+	}
+}
+
+func TestGenealogy(test *testing.T) {
+	env.SetupEnvironment([]int64{100, 100}, []int64{0, 0}, []int64{0, 0}, []bool{false}, []bool{false}, []float64{2.0, 2.0}, 0.0, 1000.0)
+	env.SetJumper(0.05, 0.05)
+	treeseq.SetupRecorder(true, 7)
+	defer env.SetJumper(0.0, 0.0)
+	defer treeseq.SetupRecorder(false, 1)
+	flies := make([]Fly, 0)
+	for i := 0; i < 20; i++ {
+		flies = append(flies, *NewFly([]int64{int64(i), 150}, []int64{110 + int64(i)}, Sex(i%2), 0))
+	}
+	pop := InitializePopulation(flies)
+	for i := 0; i < 30; i++ {
+		pop = pop.GetNextGeneration()
+	}
+	pop.SimplifyGenealogy()
+
+	// the insertions of each haplotype can be reconstructed from the genealogy
+	t := treeseq.GetTables()
+	carries := func(node int64, site int64) bool {
+		for {
+			for _, m := range t.Mutations {
+				if m.Node == node && m.Site == site {
+					return true
+				}
+			}
+			parent := int64(-1)
+			for _, e := range t.Edges {
+				if e.Child == node && e.Left <= site && site < e.Right {
+					parent = e.Parent
+				}
+			}
+			if parent == -1 {
+				return false
+			}
+			node = parent
+		}
+	}
+	for _, f := range pop.Flies {
+		for site := int64(0); site < 200; site++ {
+			if carries(f.Node1, site) != haplotypeContainsPosition(f.Hap1, site) || carries(f.Node2, site) != haplotypeContainsPosition(f.Hap2, site) {
+				test.Errorf("Genealogy is inconsistent with the haplotypes of fly %d at site %d", f.FlyNumber, site)
+			}
+		}
+	}
+}
+
+func haplotypeContainsPosition(s []int64, p int64) bool {
+	for _, v := range s {
+		if v == p {
+			return true
+		}
+	}
+	return false
+}
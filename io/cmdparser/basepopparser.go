@@ -48,21 +48,31 @@ func loadPopulation(inscount int64, popsize int64) *fly.Population {
 }
 
 /*
+Load a base population from a file; count, sex and maternal piRNAs; the insertions of the female gamete; the insertions of the male gamete.
+The first listed haplotype is passed as female gamete and becomes Hap2 of the flies (unlike the v2 format; kept for the reproducibility of existing base populations)
 Example file
 500 R 0; 1 100 200 400; 0 5 5000
 250 F 0; 2 100 400;
 250 M 0;;
 */
 func loadPopulationFromFile(file string, targetpopsize int64) *fly.Population {
-	flies := make([]fly.Fly, 0)
 	readFile, err := os.Open(file)
 	if err != nil {
 		panic(err)
 	}
+	lines := make([]string, 0)
 	fileScanner := bufio.NewScanner(readFile)
 	fileScanner.Split(bufio.ScanLines)
 	for fileScanner.Scan() {
-		line := fileScanner.Text()
+		lines = append(lines, fileScanner.Text())
+	}
+	readFile.Close()
+	if isBasePopV2(lines) {
+		return loadPopulationV2(file, lines, targetpopsize)
+	}
+
+	flies := make([]fly.Fly, 0)
+	for _, line := range lines {
 		tmp := strings.Split(line, ";")
 		if len(tmp) != 3 {
 			panic(fmt.Sprintf("Invalid base population entry %s", line))
@@ -96,7 +106,6 @@ func loadPopulationFromFile(file string, targetpopsize int64) *fly.Population {
 		}
 
 	}
-	readFile.Close()
 
 	if len(flies) != int(targetpopsize) {
 		panic("Invalid base population; population size does not match user specificiations")
@@ -104,9 +113,21 @@ func loadPopulationFromFile(file string, targetpopsize int64) *fly.Population {
 	return fly.InitializePopulation(flies)
 }
 
+/*
+Parse the insertion sites; either linear 0-based positions (e.g. 5000) or 1-based positions on named chromosomes (e.g. 2L:5001)
+*/
 func sslice2islice(sslice []string) []int64 {
 	toret := make([]int64, 0)
 	for _, s := range sslice {
+		if strings.Contains(s, ":") {
+			tmp := strings.Split(s, ":")
+			chrpos, err := strconv.ParseInt(tmp[1], 10, 64)
+			if len(tmp) != 2 || err != nil {
+				panic(fmt.Sprintf("Invalid base population character %s", s))
+			}
+			toret = append(toret, env.GetLinearCoordinate(tmp[0], chrpos))
+			continue
+		}
 		si, err := strconv.ParseInt(s, 10, 64)
 		if err != nil {
 			panic(fmt.Sprintf("Invalid base population character %s", s))
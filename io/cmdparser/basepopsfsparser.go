@@ -0,0 +1,222 @@
+package cmdparser
+
+import (
+	"fmt"
+	"invade/env"
+	"invade/fly"
+	"invade/util"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	SPECTRUMFIXED   string = "fixed"
+	SPECTRUMUNIFORM string = "uniform"
+	SPECTRUMNEUTRAL string = "neutral"
+	SPECTRUMBETA    string = "beta"
+)
+
+/*
+The distribution of the population frequencies of the insertions in the base population;
+fixed:0.1,0.5 (the sites cycle through the given frequencies), uniform, neutral (1/x) or beta:alpha,beta
+*/
+type frequencySpectrum struct {
+	kind   string
+	params []float64
+}
+
+/*
+Build a base population with standing TE variation;
+'sites' insertion sites at random positions with population frequencies drawn from the frequency spectrum;
+additionally one cluster insertion for each of the given frequencies (e.g. 0.1,0.1,0.5);
+each site is placed on the corresponding number of randomly chosen haplotypes
+*/
+func ParseBasePopSpectrum(sites int64, spectrum string, clusterfreqs string, popsize int64) *fly.Population {
+	fs := parseFrequencySpectrum(spectrum)
+	clufreqs := parseClusterFrequencies(clusterfreqs)
+	haplotypes := 2 * popsize
+	if sites+int64(len(clufreqs)) > env.GetGenomeSize() {
+		panic(fmt.Sprintf("Invalid base population; more insertion sites (%d) than positions in the genome", sites+int64(len(clufreqs))))
+	}
+	if int64(len(clufreqs)) > env.GetClusterSize() {
+		panic(fmt.Sprintf("Invalid base population; more cluster insertions (%d) than positions in the piRNA clusters", len(clufreqs)))
+	}
+
+	fhaps := make([][]int64, haplotypes)
+	for i := range fhaps {
+		fhaps[i] = []int64{}
+	}
+	// the cluster sites are chosen first, otherwise the random sites may occupy all positions of the clusters
+	used := make(map[int64]bool)
+	for _, f := range clufreqs {
+		site := getUnusedSite(used, env.GetRandomClusterSite)
+		placeSite(fhaps, site, frequencyToCount(f, haplotypes))
+	}
+	counts := fs.getCounts(sites, haplotypes)
+	for _, count := range counts {
+		site := getUnusedSite(used, env.GetRandomSite)
+		placeSite(fhaps, site, count)
+	}
+
+	flies := make([]fly.Fly, popsize)
+	for i := int64(0); i < popsize; i++ {
+		hap1 := util.UniqueSort(fhaps[2*i])
+		hap2 := util.UniqueSort(fhaps[2*i+1])
+		sex := fly.GetRandomSex()
+		nf := fly.NewFly(hap1, hap2, sex, 0)
+		flies[i] = *nf
+	}
+	return fly.InitializePopulation(flies)
+}
+
+/*
+Parse the frequency spectrum, e.g. neutral, uniform, fixed:0.1,0.5 or beta:0.5,2
+*/
+func parseFrequencySpectrum(spectrum string) frequencySpectrum {
+	tmp := strings.SplitN(strings.TrimSpace(spectrum), ":", 2)
+	kind := strings.ToLower(tmp[0])
+	params := []float64{}
+	if len(tmp) == 2 {
+		for _, s := range strings.Split(tmp[1], ",") {
+			f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				panic(fmt.Sprintf("Invalid frequency spectrum %s; parameters must be numbers", spectrum))
+			}
+			params = append(params, f)
+		}
+	}
+	switch kind {
+	case SPECTRUMUNIFORM, SPECTRUMNEUTRAL:
+		if len(params) != 0 {
+			panic(fmt.Sprintf("Invalid frequency spectrum %s; %s has no parameters", spectrum, kind))
+		}
+	case SPECTRUMFIXED:
+		if len(params) == 0 {
+			panic(fmt.Sprintf("Invalid frequency spectrum %s; provide at least one frequency, e.g. fixed:0.1", spectrum))
+		}
+		for _, f := range params {
+			if f <= 0.0 || f > 1.0 {
+				panic(fmt.Sprintf("Invalid frequency spectrum %s; frequencies must be in (0,1]", spectrum))
+			}
+		}
+	case SPECTRUMBETA:
+		if len(params) != 2 || params[0] <= 0.0 || params[1] <= 0.0 {
+			panic(fmt.Sprintf("Invalid frequency spectrum %s; provide two positive shape parameters, e.g. beta:0.5,2", spectrum))
+		}
+	default:
+		panic(fmt.Sprintf("Invalid frequency spectrum %s; must be one of fixed, uniform, neutral or beta", spectrum))
+	}
+	return frequencySpectrum{kind: kind, params: params}
+}
+
+/*
+Parse the frequencies of the cluster insertions, e.g. 0.1,0.1,0.5; one cluster insertion per frequency
+*/
+func parseClusterFrequencies(s string) []float64 {
+	toret := []float64{}
+	if strings.TrimSpace(s) == "" {
+		return toret
+	}
+	for _, t := range strings.Split(s, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		if err != nil || f <= 0.0 || f > 1.0 {
+			panic(fmt.Sprintf("Invalid frequency of cluster insertion %s; must be in (0,1]", t))
+		}
+		toret = append(toret, f)
+	}
+	return toret
+}
+
+/*
+Draw the number of haplotypes carrying each of the sites;
+uniform and neutral yield segregating sites (1 to n-1 haplotypes), with the neutral spectrum the probability of k haplotypes is proportional to 1/k
+*/
+func (fs frequencySpectrum) getCounts(sites int64, haplotypes int64) []int64 {
+	counts := make([]int64, sites)
+	var neutral []float64
+	if fs.kind == SPECTRUMNEUTRAL {
+		neutral = getNeutralCumulative(haplotypes)
+	}
+	for i := int64(0); i < sites; i++ {
+		switch fs.kind {
+		case SPECTRUMFIXED:
+			counts[i] = frequencyToCount(fs.params[i%int64(len(fs.params))], haplotypes)
+		case SPECTRUMUNIFORM:
+			counts[i] = getSegregatingCount(rand.Int63n(getSegregatingClasses(haplotypes)))
+		case SPECTRUMNEUTRAL:
+			r := rand.Float64() * neutral[len(neutral)-1]
+			counts[i] = getSegregatingCount(int64(sort.SearchFloat64s(neutral, r)))
+		case SPECTRUMBETA:
+			counts[i] = frequencyToCount(util.Beta(fs.params[0], fs.params[1]), haplotypes)
+		}
+	}
+	return counts
+}
+
+/*
+The cumulative weights 1/k of the neutral frequency spectrum for k=1..n-1
+*/
+func getNeutralCumulative(haplotypes int64) []float64 {
+	classes := getSegregatingClasses(haplotypes)
+	cum := make([]float64, classes)
+	sum := 0.0
+	for k := int64(0); k < classes; k++ {
+		sum += 1.0 / float64(k+1)
+		cum[k] = sum
+	}
+	return cum
+}
+
+/*
+Number of classes of segregating sites (1 to n-1 haplotypes); with a single haplotype only the class 1 remains
+*/
+func getSegregatingClasses(haplotypes int64) int64 {
+	if haplotypes < 2 {
+		return 1
+	}
+	return haplotypes - 1
+}
+
+func getSegregatingCount(class int64) int64 {
+	return class + 1
+}
+
+/*
+Translate a population frequency into a number of haplotypes; at least one haplotype carries the insertion
+*/
+func frequencyToCount(freq float64, haplotypes int64) int64 {
+	count := int64(math.Round(freq * float64(haplotypes)))
+	if count < 1 {
+		count = 1
+	}
+	if count > haplotypes {
+		count = haplotypes
+	}
+	return count
+}
+
+/*
+Get a site which is not yet used by another insertion of the base population;
+terminates as the number of sites is checked against the size of the genome and the clusters (see ParseBasePopSpectrum)
+*/
+func getUnusedSite(used map[int64]bool, random func() int64) int64 {
+	for {
+		site := random()
+		if !used[site] {
+			used[site] = true
+			return site
+		}
+	}
+}
+
+/*
+Insert the site into 'count' randomly chosen haplotypes
+*/
+func placeSite(fhaps [][]int64, site int64, count int64) {
+	for _, h := range rand.Perm(len(fhaps))[:count] {
+		fhaps[h] = append(fhaps[h], site)
+	}
+}
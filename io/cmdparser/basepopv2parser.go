@@ -0,0 +1,163 @@
+package cmdparser
+
+import (
+	"fmt"
+	"invade/env"
+	"invade/fly"
+	"invade/util"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+Header of the v2 format of the base population
+*/
+const BASEPOPV2HEADER = "#invade-basepop v2"
+
+/*
+A line of the v2 base population; 'count' flies of the given sex with the given maternal piRNAs and the two haplotypes
+*/
+type basePopEntry struct {
+	count      int64
+	sex        string
+	matpirna   int64
+	hap1       []int64
+	hap2       []int64
+	duplicates []string // insertions listed multiple times in a haplotype
+}
+
+/*
+Is the base population file in the v2 format? i.e. the first line that is not empty is the header
+*/
+func isBasePopV2(lines []string) bool {
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		return strings.HasPrefix(strings.TrimSpace(line), BASEPOPV2HEADER)
+	}
+	return false
+}
+
+/*
+Load a base population in the v2 format; insertions are provided as 1-based coordinates on the chromosomes, grouped by chromosome.
+Comments (#) and empty lines are ignored. The first listed haplotype becomes Hap1 and the second Hap2 of the flies (as in the VCF; unlike the v1 format).
+Example file
+#invade-basepop v2
+# count sex maternal-piRNAs; haplotype 1; haplotype 2
+500 R 0; 2L:100,200,400; X:5,5000
+250 F 0; 2L:100 2R:400;
+250 M 0;;
+*/
+func loadPopulationV2(file string, lines []string, targetpopsize int64) *fly.Population {
+	flies := make([]fly.Fly, 0)
+	for i, line := range lines {
+		entry, err := parseBasePopLineV2(line)
+		if err != nil {
+			panic(fmt.Sprintf("Invalid base population %s, line %d: %s", file, i+1, err.Error()))
+		}
+		if entry == nil {
+			continue // comment or empty line
+		}
+		for _, d := range entry.duplicates {
+			util.InvadeLogger.Printf("Warning: base population %s, line %d: insertion %s is listed multiple times in a haplotype; will be used once", file, i+1, d)
+		}
+		for k := int64(0); k < entry.count; k++ {
+			f := fly.NewFly(entry.hap2, entry.hap1, getSex(entry.sex), entry.matpirna) // NewFly expects the female gamete first (becomes Hap2)
+			flies = append(flies, *f)
+		}
+	}
+	if len(flies) != int(targetpopsize) {
+		panic(fmt.Sprintf("Invalid base population %s; population size %d does not match user specifications %d", file, len(flies), targetpopsize))
+	}
+	return fly.InitializePopulation(flies)
+}
+
+/*
+Parse a line of the v2 base population; returns nil for comments and empty lines
+*/
+func parseBasePopLineV2(line string) (*basePopEntry, error) {
+	if ci := strings.Index(line, "#"); ci >= 0 {
+		line = line[:ci]
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, nil
+	}
+	tmp := strings.Split(line, ";")
+	if len(tmp) != 3 {
+		return nil, fmt.Errorf("expected three fields separated by ';' (count sex maternal-piRNAs; haplotype 1; haplotype 2), got %d", len(tmp))
+	}
+	header := strings.Fields(tmp[0])
+	if len(header) != 3 {
+		return nil, fmt.Errorf("expected count, sex and maternal piRNAs, got '%s'", tmp[0])
+	}
+	count, err := strconv.ParseInt(header[0], 10, 64)
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("invalid count '%s'; must be a positive integer", header[0])
+	}
+	sex := strings.ToUpper(header[1])
+	if sex != "M" && sex != "F" && sex != "R" {
+		return nil, fmt.Errorf("invalid sex '%s'; must be M, F or R", header[1])
+	}
+	matpirna, err := strconv.ParseInt(header[2], 10, 64)
+	if err != nil || matpirna < 0 {
+		return nil, fmt.Errorf("invalid maternal piRNAs '%s'; must be a non-negative integer", header[2])
+	}
+	entry := basePopEntry{count: count, sex: sex, matpirna: matpirna}
+	var dups1, dups2 []string
+	if entry.hap1, dups1, err = parseHaplotypeV2(tmp[1]); err != nil {
+		return nil, fmt.Errorf("haplotype 1: %s", err.Error())
+	}
+	if entry.hap2, dups2, err = parseHaplotypeV2(tmp[2]); err != nil {
+		return nil, fmt.Errorf("haplotype 2: %s", err.Error())
+	}
+	entry.duplicates = append(dups1, dups2...)
+	return &entry, nil
+}
+
+/*
+Parse a haplotype of the v2 base population, e.g. '2L:100,200 X:5'; positions are 1-based and validated against the genome.
+Returns the sorted linear positions and the insertions that were listed multiple times
+*/
+func parseHaplotypeV2(s string) ([]int64, []string, error) {
+	names := env.GetChromosomeNames()
+	sizes := env.GetChromosomeSizes()
+	chrsize := make(map[string]int64)
+	for i, n := range names {
+		chrsize[n] = sizes[i]
+	}
+	sites := make(map[int64]bool)
+	dups := make([]string, 0)
+	for _, group := range strings.Fields(s) {
+		tmp := strings.Split(group, ":")
+		if len(tmp) != 2 {
+			return nil, nil, fmt.Errorf("invalid insertions '%s'; must be chrom:pos,pos,..", group)
+		}
+		size, ok := chrsize[tmp[0]]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown chromosome '%s'; known chromosomes are %v", tmp[0], names)
+		}
+		for _, ps := range strings.Split(tmp[1], ",") {
+			pos, err := strconv.ParseInt(ps, 10, 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid position '%s' on chromosome %s", ps, tmp[0])
+			}
+			if pos < 1 || pos > size {
+				return nil, nil, fmt.Errorf("position %d outside of chromosome %s (1-%d)", pos, tmp[0], size)
+			}
+			lin := env.GetLinearCoordinate(tmp[0], pos)
+			if sites[lin] {
+				dups = append(dups, fmt.Sprintf("%s:%d", tmp[0], pos))
+			}
+			sites[lin] = true
+		}
+	}
+	toret := make([]int64, 0, len(sites))
+	for site := range sites {
+		toret = append(toret, site)
+	}
+	sort.Slice(toret, func(i, j int) bool { return toret[i] < toret[j] })
+	return toret, dups, nil
+}
@@ -0,0 +1,154 @@
+package cmdparser
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"invade/env"
+	"invade/fly"
+	"invade/util"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/*
+Load a base population from a VCF of TE insertions (may be gzip compressed);
+each record is an insertion site (CHROM and POS are translated into the linear coordinates), each sample a fly.
+The first allele of the genotype becomes Hap1, the second Hap2; any non-reference allele is an insertion, missing alleles are treated as absent.
+If resample is true, popsize flies are randomly drawn (with replacement) from the samples; otherwise the number of samples must match the popsize.
+The sex of the flies is random
+*/
+func ParseBasePopVCF(file string, popsize int64, resample bool) *fly.Population {
+	haps1, haps2 := readVCFHaplotypes(file)
+	samplecount := int64(len(haps1))
+	if samplecount == 0 {
+		panic(fmt.Sprintf("Invalid base population VCF %s; no samples", file))
+	}
+	if !resample && samplecount != popsize {
+		panic(fmt.Sprintf("Invalid base population VCF %s; number of samples %d does not match the population size %d (use resampling)", file, samplecount, popsize))
+	}
+	flies := make([]fly.Fly, popsize)
+	for i := int64(0); i < popsize; i++ {
+		s := i
+		if resample {
+			s = rand.Int63n(samplecount)
+		}
+		nf := fly.NewFly(haps2[s], haps1[s], fly.GetRandomSex(), 0) // NewFly expects the female gamete first (becomes Hap2)
+		flies[i] = *nf
+	}
+	return fly.InitializePopulation(flies)
+}
+
+/*
+Read the haplotypes of each sample from a VCF; returns the first and the second haplotype of each sample (sorted linear positions)
+*/
+func readVCFHaplotypes(file string) ([][]int64, [][]int64) {
+	readFile, err := os.Open(file)
+	if err != nil {
+		panic(err)
+	}
+	defer readFile.Close()
+	var reader io.Reader = readFile
+	if strings.HasSuffix(file, ".gz") {
+		gz, err := gzip.NewReader(readFile)
+		if err != nil {
+			panic(err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	var haps1, haps2 [][]int64
+	unphased := 0
+	header := false
+	fileScanner := bufio.NewScanner(reader)
+	fileScanner.Buffer(make([]byte, 1024*1024), 64*1024*1024) // lines with many samples may be long
+	lineno := 0
+	for fileScanner.Scan() {
+		lineno++
+		line := fileScanner.Text()
+		if strings.HasPrefix(line, "##") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if strings.HasPrefix(line, "#CHROM") {
+			if len(fields) < 10 {
+				panic(fmt.Sprintf("Invalid base population VCF %s, line %d: no sample columns", file, lineno))
+			}
+			haps1 = make([][]int64, len(fields)-9)
+			haps2 = make([][]int64, len(fields)-9)
+			header = true
+			continue
+		}
+		if !header {
+			panic(fmt.Sprintf("Invalid base population VCF %s, line %d: record before the #CHROM header", file, lineno))
+		}
+		if len(fields) != len(haps1)+9 {
+			panic(fmt.Sprintf("Invalid base population VCF %s, line %d: expected %d columns, got %d", file, lineno, len(haps1)+9, len(fields)))
+		}
+		chrpos, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			panic(fmt.Sprintf("Invalid base population VCF %s, line %d: invalid position %s", file, lineno, fields[1]))
+		}
+		pos := getVCFLinearCoordinate(file, lineno, fields[0], chrpos)
+		gtidx := indexOf(strings.Split(fields[8], ":"), "GT")
+		if gtidx < 0 {
+			panic(fmt.Sprintf("Invalid base population VCF %s, line %d: no GT field", file, lineno))
+		}
+		for s, sample := range fields[9:] {
+			sf := strings.Split(sample, ":")
+			if gtidx >= len(sf) {
+				continue // missing genotype
+			}
+			gt := sf[gtidx]
+			if strings.Contains(gt, "/") {
+				unphased++
+			}
+			alleles := strings.FieldsFunc(gt, func(r rune) bool { return r == '|' || r == '/' })
+			if len(alleles) > 0 && isInsertionAllele(alleles[0]) {
+				haps1[s] = append(haps1[s], pos)
+			}
+			if len(alleles) > 1 && isInsertionAllele(alleles[1]) {
+				haps2[s] = append(haps2[s], pos)
+			}
+		}
+	}
+	if unphased > 0 {
+		util.InvadeLogger.Printf("Warning: base population VCF %s contains %d unphased genotypes; alleles are assigned to the haplotypes in the given order", file, unphased)
+	}
+	for s := range haps1 {
+		haps1[s] = util.UniqueSort(haps1[s])
+		haps2[s] = util.UniqueSort(haps2[s])
+	}
+	return haps1, haps2
+}
+
+func getVCFLinearCoordinate(file string, lineno int, chrm string, chrpos int64) int64 {
+	names := env.GetChromosomeNames()
+	sizes := env.GetChromosomeSizes()
+	for i, n := range names {
+		if n == chrm {
+			if chrpos < 1 || chrpos > sizes[i] {
+				panic(fmt.Sprintf("Invalid base population VCF %s, line %d: position %d outside of chromosome %s (1-%d)", file, lineno, chrpos, chrm, sizes[i]))
+			}
+			return env.GetLinearCoordinate(chrm, chrpos)
+		}
+	}
+	panic(fmt.Sprintf("Invalid base population VCF %s, line %d: unknown chromosome %s; known chromosomes are %v", file, lineno, chrm, names))
+}
+
+func isInsertionAllele(a string) bool {
+	return a != "0" && a != "."
+}
+
+func indexOf(s []string, v string) int {
+	for i, e := range s {
+		if e == v {
+			return i
+		}
+	}
+	return -1
+}
@@ -1,14 +1,18 @@
 package cmdparser
 
 import (
+	"errors"
 	"flag"
-	"os"
+	"fmt"
+	"invade/fly"
 	"strings"
 )
 
 type CommandLineParameters struct {
 	ArgString        string
 	Silent           bool
+	Explain          bool // print the resolved environment and exit
+	AnalyticsColumns bool // the predictions of the trap model as reference columns of the main table
 	Popsize          int64
 	Genome           string
 	Cluster          string
@@ -21,6 +25,13 @@ type CommandLineParameters struct {
 	Steps            int64   // report output each Steps generations
 	Generations      int64
 	BasePop          string
+	BasePopVCF       string // base population from a VCF of TE insertions
+	BasePopResample  bool   // randomly draw the flies of the base population from the samples of the VCF
+	BasePopSites     int64  // number of insertion sites of a base population with standing variation
+	BasePopSpectrum  string // frequency spectrum of the insertion sites of the base population
+	BasePopCluster   string // frequencies of the cluster insertions of the base population
+	StopWhen         string // user-defined stop condition
+	StopExtra        int64  // generations simulated after the stop condition is met
 	Noxcluins        bool
 	Multiplicative   bool
 	SampleID         string
@@ -36,82 +47,231 @@ type CommandLineParameters struct {
 	FileTally        string
 	FileDebug        string
 	FileSFS          string
+	DysU             float64 // multiplier of the transposition rate in dysgenic flies
+	DysSterility     float64 // probability that dysgenic flies are sterile
+	PTrigger         float64 // probability that trigger + paramutable insertions start piRNA production
+	PParaEstablish   float64 // probability that maternal piRNAs establish paramutation
+	PParaMaintain    float64 // probability that paramutation is maintained
+	PParaLoss        float64 // probability of a spontaneous loss of paramutation
+	PirnaQuant       bool    // quantitative piRNA levels instead of a binary piRNA status
+	PirnaDeposition  float64 // fraction of the maternal piRNA level deposited in the offspring
+	PirnaProduction  float64 // piRNA level produced per cluster insertion (or paramutated insertion)
+	PirnaSaturation  float64 // piRNA level at which TEs are fully silenced
+	ZygoticEfficacy  float64 // efficacy of zygotic piRNAs in flies without maternal piRNAs
+	FilePirna        string
+	FileAge          string
+	PirnaModel       string // rules for the biogenesis of piRNAs
+	FileTS           string // prefix of the output files for the tree sequence
+	TSSimplify       int64  // simplify the tree sequence every TSSimplify generations
+	FileVCF          string // prefix of the VCF output files
+	VCFSample        int64  // number of flies in the VCF; 0 = all
+	FilePoolseq      string
+	PoolFlies        int64   // number of flies in the pool
+	PoolCoverage     float64 // average coverage of the pool
+	PoolDispersion   float64 // size parameter of the negative binomial coverage; 0 = Poisson
+	FileStats        string
+	FileLD           string
+	FileSummary      string
+	FileSumstats     string
+	FileAggregate    string
+	AggregateFailed  string  // handling of replicates which ended early; exclude or carry
+	AggregateQuant   string  // quantiles of the aggregation across replicates
+	LDMinFreq        float64 // minimum minor allele frequency of sites for LD
+	LDMaxDistance    int64   // maximum distance between the sites of a pair
+	LDBinSize        int64   // size of the distance bins of the LD decay
+	Format           string  // format of the main table; legacy, tsv, csv, jsonl
+	FileMain         string  // output file of the main table; stdout if empty
+	FileInfo         string  // arguments, seed and analytical predictions; e.g. for the structured formats of the main table
 }
 
-func ParseCommandLine() *CommandLineParameters {
-	test := os.Args[1:]
-	argstring := strings.Join(test, " ")
+/*
+Parse the parameters of the simulations; used by the subcommands 'simulate' and 'validate', which share the parameters;
+without a subcommand the parameters are passed to 'simulate'. Missing or invalid parameters yield an error (after the usage is printed)
+*/
+func ParseCommandLine(command string, args []string) (*CommandLineParameters, error) {
+	argstring := strings.Join(args, " ")
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: invade %s [parameters]\n\n%s\n\nParameters:\n", command, GetSubcommandDescription(command))
+		fs.PrintDefaults()
+	}
 	// Mandatory parameters
-	popsize := flag.Int64("N", -1, "mandatory; the population size")
-	genome := flag.String("genome", "", "mandatory; the genomic landscape; e.g. 'MB:2,3,1,5' specifiies four chromosomes with sizes of 2,3,1,5 Mb")
-	generations := flag.Int64("gen", -1, "mandatory; run the simulations for '--gen' generations")
-	basepop := flag.String("basepop", "", "mandatory; the segregating insertions in the starting population; either number (e.g. 100) or file")
+	popsize := fs.Int64("N", -1, "mandatory; the population size")
+	genome := fs.String("genome", "", "mandatory; the genomic landscape; e.g. 'MB:2,3,1,5' specifiies four chromosomes with sizes of 2,3,1,5 Mb; named chromosomes e.g. '2L:23.5Mb,2R:25.3Mb' or a FASTA index file (.fai)")
+	generations := fs.Int64("gen", -1, "mandatory; run the simulations for '--gen' generations")
+	basepop := fs.String("basepop", "", "mandatory; the segregating insertions in the starting population; either number (e.g. 100) or file")
+	basepopvcf := fs.String("basepop-vcf", "", "alternative to --basepop; the starting population from a VCF of phased TE insertions; each sample is a fly")
+	basepopresample := fs.Bool("basepop-resample", false, "randomly draw --N flies (with replacement) from the samples of --basepop-vcf; otherwise the number of samples must match --N")
+	basepopsites := fs.Int64("basepop-sites", 0, "alternative to --basepop; the number of segregating insertion sites in the starting population; the frequencies are drawn from --basepop-sfs")
+	basepopsfs := fs.String("basepop-sfs", "neutral", "the frequency spectrum of --basepop-sites; 'fixed:0.1,0.5' (sites cycle through the frequencies), 'uniform', 'neutral' (1/x) or 'beta:0.5,2'")
+	basepopcluster := fs.String("basepop-cluster", "", "additional cluster insertions in the starting population, one per frequency; e.g. '0.1,0.1,0.5'")
 
 	// Optional parameters
-	transrate := flag.Float64("u", 0.0, "the transposition rate")
-	cluster := flag.String("cluster", "", "piRNA clusters; e.g. 'kb:1,1,1,1' specifies a cluster of 1kb at the beginning of each chromosome")
-	sampleid := flag.String("sampleid", "", "the ID of the sample; will be a help in R to group samples like with facete_grid()")
-	refregion := flag.String("ref-region", "", "reference region; e.g. 'kb:1,1,1,1' specifies a reference region of 1kb at the end of each chromosome")
-	rr := flag.String("rr", "", "the recombination rate per chromosome in cm/Mb; e.g. '3,4,4,5' ")
-	paramutSites := flag.String("paramutation", "", "paramutable sites, e.g. '10:1,2,9' with modulo 10 the residuals 1,2,9 are paramutable ")
-	triggerSites := flag.String("trigger", "", "triggers sites, e.g. '10:3,4,5' with modulo 10 the residuals 3,4,5 trigger the production of piRNAs ")
-	x := flag.Float64("x", 0.0, "the deleterious effect of a single TE insertions")
-	t := flag.Float64("t", 1.0, "the synergistic effect of TE insertions")
-	noxcluins := flag.Bool("no-x-cluins", false, "cluster insertions incur no negative effects")
-	multiplicative := flag.Bool("multiplicative", false, "multiplicative fitness decay (instead of linear, which is the default")
+	stopwhen := fs.String("stop-when", "", "stop a replicate early; e.g. 'phase==inac for 100', 'fwpirna>0.99' or 'stationary(avtes,200,0.05)' (avtes changes by less than 5% over a window of 200 generations); variables are named like the columns of the main output; combine with && and ||")
+	stopextra := fs.Int64("stop-extra", 0, "continue the simulation for this number of generations after the --stop-when condition is met")
+	transrate := fs.Float64("u", 0.0, "the transposition rate")
+	cluster := fs.String("cluster", "", "piRNA clusters; e.g. 'kb:1,1,1,1' specifies a cluster of 1kb at the beginning of each chromosome")
+	sampleid := fs.String("sampleid", "", "the ID of the sample; will be a help in R to group samples like with facete_grid()")
+	refregion := fs.String("ref-region", "", "reference region; e.g. 'kb:1,1,1,1' specifies a reference region of 1kb at the end of each chromosome")
+	rr := fs.String("rr", "", "the recombination rate per chromosome in cm/Mb; e.g. '3,4,4,5' ")
+	paramutSites := fs.String("paramutation", "", "paramutable sites, e.g. '10:1,2,9' with modulo 10 the residuals 1,2,9 are paramutable ")
+	triggerSites := fs.String("trigger", "", "triggers sites, e.g. '10:3,4,5' with modulo 10 the residuals 3,4,5 trigger the production of piRNAs ")
+	x := fs.Float64("x", 0.0, "the deleterious effect of a single TE insertions")
+	t := fs.Float64("t", 1.0, "the synergistic effect of TE insertions")
+	noxcluins := fs.Bool("no-x-cluins", false, "cluster insertions incur no negative effects")
+	multiplicative := fs.Bool("multiplicative", false, "multiplicative fitness decay (instead of linear, which is the default")
 	//ignoreFailed := flag.Bool("ignored-failed", false, "ignore invasions where the TE did not get established")
-	transrateResidual := flag.Float64("uc", 0.0, "the transposition rate in the presence of piRNAs")
-	steps := flag.Int64("steps", 20, "report the output at each '--steps' generations")
-	replicates := flag.Int64("rep", 1, "the number of replicates")
-	reploffset := flag.Int64("replicate-offset", 1, "starting index of the replicates; may be used for pseudo-parallelization)")
-	fileMHP := flag.String("file-mhp", "", "optional output file: position and population frequency of each insertion")
-	fileDebug := flag.String("file-debug", "", "optional output file for debugging various aspects")
-	fileSFS := flag.String("file-sfs", "", "optional output file: site frequency spectra of TE insertions")
-	fileTally := flag.String("file-tally", "", "optional output file: count of insertions per individual")
-	maxins := flag.Int64("max-insertions", 10000, "the maximum number of insertions")
-	minw := flag.Float64("min-w", 0.1, "the minimum frequency of an average individual in the population")
-	seed := flag.Int64("seed", -1, "seed for the random number generator")
-	threads := flag.Int64("threads", 1, "number of threads")
-	silent := flag.Bool("silent", false, "suppress output")
-	flag.Parse()
+	transrateResidual := fs.Float64("uc", 0.0, "the transposition rate in the presence of piRNAs")
+	dysu := fs.Float64("dys-u", 1.0, "hybrid dysgenesis; multiplier of the transposition rate in dysgenic flies (offspring of mothers without piRNAs and fathers with TEs)")
+	dyssterility := fs.Float64("dys-sterility", 0.0, "hybrid dysgenesis; probability that a dysgenic fly is sterile")
+	ptrigger := fs.Float64("p-trigger", 1.0, "probability that trigger + paramutable insertions start the production of piRNAs")
+	pparaestablish := fs.Float64("p-para-establish", 1.0, "probability that maternal piRNAs paramutate an insertion in a paramutable locus (mother without paramutated loci)")
+	pparamaintain := fs.Float64("p-para-maintain", 1.0, "probability that paramutation is maintained (mother with paramutated loci)")
+	pparaloss := fs.Float64("p-para-loss", 0.0, "probability of a spontaneous loss of paramutation per generation")
+	pirnaquant := fs.Bool("pirna-quant", false, "quantitative piRNA levels; the transposition rate is interpolated between '--u' and '--uc' based on the piRNA level")
+	pirnadeposition := fs.Float64("pirna-deposition", 0.5, "quantitative piRNA levels; fraction of the maternal piRNA level deposited in the offspring")
+	pirnaproduction := fs.Float64("pirna-production", 1.0, "quantitative piRNA levels; piRNA level produced by each cluster insertion (or paramutated insertion)")
+	pirnasaturation := fs.Float64("pirna-saturation", 1.0, "quantitative piRNA levels; piRNA level at which TEs are fully silenced")
+	zygeff := fs.Float64("zygotic-efficacy", 1.0, "efficacy of piRNAs solely produced zygotically, i.e. without maternal piRNAs (e.g. paternally inherited cluster insertions); 0.0 = silencing only in the next generation")
+	pirnamodel := fs.String("pirna-model", "trap", fmt.Sprintf("the model for the biogenesis of piRNAs; one of '%s'", strings.Join(fly.GetPirnaModelNames(), "', '")))
+	steps := fs.Int64("steps", 20, "report the output at each '--steps' generations")
+	replicates := fs.Int64("rep", 1, "the number of replicates")
+	reploffset := fs.Int64("replicate-offset", 1, "starting index of the replicates; may be used for pseudo-parallelization)")
+	format := fs.String("format", "legacy", "format of the main table; 'legacy' (with '|' separator columns), 'tsv', 'csv' or 'jsonl'; the structured formats have a single header line, the arguments, the seed and the analytical predictions are solely logged (see --file-info)")
+	fileMain := fs.String("file-main", "", "optional output file for the main table; default is stdout")
+	fileInfo := fs.String("file-info", "", "optional output file: the arguments, the version, the seed and the analytical predictions, as in the header of the legacy main table")
+	fileMHP := fs.String("file-mhp", "", "optional output file: position and population frequency of each insertion")
+	fileDebug := fs.String("file-debug", "", "optional output file for debugging various aspects")
+	fileSFS := fs.String("file-sfs", "", "optional output file: site frequency spectra of TE insertions")
+	filePirna := fs.String("file-pirna", "", "optional output file: distribution of piRNA levels")
+	fileAge := fs.String("file-age", "", "optional output file: age distribution of TE insertions")
+	fileTS := fs.String("file-ts", "", "optional output files: prefix for the genealogy as tree sequence (tskit text tables)")
+	tssimplify := fs.Int64("ts-simplify", 100, "simplify the tree sequence every '--ts-simplify' generations")
+	fileVCF := fs.String("file-vcf", "", "optional output files: genotypes of the flies as VCF; one file per recorded generation and replicate")
+	vcfsample := fs.Int64("vcf-sample", 0, "number of flies in the VCF, a random sample of the population; 0 = all flies")
+	filePoolseq := fs.String("file-poolseq", "", "optional output file: simulated pool-seq data; estimated and true population frequencies of the insertions")
+	poolflies := fs.Int64("pool-n", 100, "pool-seq; number of flies in the pool")
+	poolcov := fs.Float64("pool-coverage", 50.0, "pool-seq; average coverage")
+	pooldisp := fs.Float64("pool-dispersion", 0.0, "pool-seq; size parameter of the negative binomial distribution of the coverage; 0 = Poisson distributed coverage")
+	fileStats := fs.String("file-stats", "", "optional output file: population genetic statistics (theta, Tajima's D, heterozygosity) for each category of insertions")
+	fileSummary := fs.String("file-summary", "", "optional output file: one line per replicate with the generations of the phase transitions, the first cluster insertion, the peak copy number, the copy number at silencing and the final status")
+	fileSumstats := fs.String("file-sumstats", "", "optional output file: summary statistics and status of the last generation of each replicate (copy number, cluster insertions, fixed insertions, binned site frequency spectrum); e.g. for 'invade abc'")
+	fileAggregate := fs.String("file-aggregate", "", "optional output file: for each recorded generation the mean, standard deviation and quantiles of each numeric column of the main table across replicates; a generation is written once the last replicate passed it")
+	aggregateFailed := fs.String("aggregate-failed", "exclude", "aggregation; replicates which ended early (failed or stopped) are either excluded from later generations ('exclude') or contribute their last values ('carry')")
+	aggregateQuant := fs.String("aggregate-quantiles", "0.025,0.5,0.975", "aggregation; the quantiles across replicates")
+	fileLD := fs.String("file-ld", "", "optional output file: LD decay (D, D', r2) among insertion sites, binned by distance")
+	ldminfreq := fs.Float64("ld-min-freq", 0.05, "LD; minimum minor allele frequency of an insertion site")
+	ldmaxdist := fs.Int64("ld-max-dist", 100000, "LD; maximum distance between two insertion sites (bp)")
+	ldbin := fs.Int64("ld-bin", 10000, "LD; size of the distance bins (bp)")
+	fileTally := fs.String("file-tally", "", "optional output file: count of insertions per individual")
+	maxins := fs.Int64("max-insertions", 10000, "the maximum number of insertions")
+	minw := fs.Float64("min-w", 0.1, "the minimum frequency of an average individual in the population")
+	seed := fs.Int64("seed", -1, "seed for the random number generator")
+	threads := fs.Int64("threads", 1, "number of threads")
+	silent := fs.Bool("silent", false, "suppress output")
+	analyticscols := fs.Bool("analytics-columns", false, "add reference columns to the main table; the insertions per fly predicted by the trap model (pred_avtes) and the insertions per fly relative to the prediction (rel_avtes)")
+	explain := fs.Bool("explain", false, "print the resolved environment (genome layout, clusters and reference regions, covered fractions, recombination lambdas), the expected novel insertions per gamete of the base population and the predictions of the trap model; then exit")
+	fs.Parse(args)
 
 	// basic checks if parameters are suitable
 	if *popsize < 2 {
-		panic("Provide a suitable population size --N; must be larger than 1")
+		return nil, usageError(fs, "Provide a suitable population size --N; must be larger than 1")
 	}
 	if *transrate < 0.0 {
-		panic("Provide a suitable transposition rate --u; must be larger or equal to 0.0")
+		return nil, usageError(fs, "Provide a suitable transposition rate --u; must be larger or equal to 0.0")
 	}
 	if *transrateResidual < 0.0 {
-		panic("Provide a suitable residual transposition rate --uc; must be larger or equal to 0.0")
+		return nil, usageError(fs, "Provide a suitable residual transposition rate --uc; must be larger or equal to 0.0")
 	}
 	if *x < 0.0 {
-		panic("Provide a suitable deleterious effect of TEs --x; must be larger or equal to 0.0")
+		return nil, usageError(fs, "Provide a suitable deleterious effect of TEs --x; must be larger or equal to 0.0")
 	}
 	if *t < 1.0 {
-		panic("Provide a suitable epistatic effect of TEs --t; must be larger or equal to 1.0")
+		return nil, usageError(fs, "Provide a suitable epistatic effect of TEs --t; must be larger or equal to 1.0")
+	}
+	if *dysu < 0.0 {
+		return nil, usageError(fs, "Provide a suitable transposition rate multiplier for dysgenic flies --dys-u; must be larger or equal to 0.0")
+	}
+	if *dyssterility < 0.0 || *dyssterility > 1.0 {
+		return nil, usageError(fs, "Provide a suitable sterility of dysgenic flies --dys-sterility; must be between 0.0 and 1.0")
+	}
+	for _, p := range []float64{*ptrigger, *pparaestablish, *pparamaintain, *pparaloss} {
+		if p < 0.0 || p > 1.0 {
+			return nil, usageError(fs, "Provide suitable probabilities for paramutation --p-trigger, --p-para-establish, --p-para-maintain, --p-para-loss; must be between 0.0 and 1.0")
+		}
+	}
+	if *pirnadeposition < 0.0 || *pirnadeposition > 1.0 {
+		return nil, usageError(fs, "Provide a suitable maternal deposition of piRNAs --pirna-deposition; must be between 0.0 and 1.0")
+	}
+	if *pirnaproduction < 0.0 {
+		return nil, usageError(fs, "Provide a suitable production of piRNAs --pirna-production; must be larger or equal to 0.0")
+	}
+	if *pirnasaturation <= 0.0 {
+		return nil, usageError(fs, "Provide a suitable saturation level of piRNAs --pirna-saturation; must be larger than 0.0")
+	}
+	if *zygeff < 0.0 || *zygeff > 1.0 {
+		return nil, usageError(fs, "Provide a suitable efficacy of zygotic piRNAs --zygotic-efficacy; must be between 0.0 and 1.0")
 	}
 	if *genome == "" {
-		panic("Provide a suitable genome --genome")
+		return nil, usageError(fs, "Provide a suitable genome --genome")
+	}
+	if *basepop == "" && *basepopvcf == "" && *basepopsites == 0 && *basepopcluster == "" {
+		return nil, usageError(fs, "Provide a suitable base population --basepop, --basepop-vcf or --basepop-sites")
+	}
+	if countBasePopSources(*basepop, *basepopvcf, *basepopsites, *basepopcluster) > 1 {
+		return nil, usageError(fs, "Provide a single base population; --basepop, --basepop-vcf and --basepop-sites/--basepop-cluster are mutually exclusive")
 	}
-	if *basepop == "" {
-		panic("Provide a suitable base population --basepop")
+	if *basepopsites < 0 {
+		return nil, usageError(fs, "Provide a suitable number of insertion sites --basepop-sites; must be larger or equal to 0")
 	}
 	if *generations < 1 {
-		panic("Provide a suitable number of generations --gen")
+		return nil, usageError(fs, "Provide a suitable number of generations --gen")
+	}
+	if *tssimplify < 1 {
+		return nil, usageError(fs, "Provide a suitable simplification interval --ts-simplify; must be larger or equal to 1")
+	}
+	if *vcfsample < 0 {
+		return nil, usageError(fs, "Provide a suitable sample size for the VCF --vcf-sample; must be larger or equal to 0")
+	}
+	if *poolflies < 1 {
+		return nil, usageError(fs, "Provide a suitable number of flies in the pool --pool-n; must be larger than 0")
+	}
+	if *poolcov <= 0.0 {
+		return nil, usageError(fs, "Provide a suitable coverage of the pool --pool-coverage; must be larger than 0.0")
+	}
+	if *pooldisp < 0.0 {
+		return nil, usageError(fs, "Provide a suitable dispersion of the coverage --pool-dispersion; must be larger or equal to 0.0")
+	}
+	if *ldminfreq <= 0.0 || *ldminfreq > 0.5 {
+		return nil, usageError(fs, "Provide a suitable minimum frequency for LD --ld-min-freq; must be larger than 0.0 and smaller or equal to 0.5")
+	}
+	if *ldmaxdist < 1 || *ldbin < 1 {
+		return nil, usageError(fs, "Provide a suitable maximum distance --ld-max-dist and bin size --ld-bin for LD; must be larger than 0")
+	}
+	if *format != "legacy" && *format != "tsv" && *format != "csv" && *format != "jsonl" {
+		return nil, usageError(fs, "Provide a suitable format of the main table --format; must be 'legacy', 'tsv', 'csv' or 'jsonl'")
 	}
 	if *steps < 1 {
-		panic("Provide suitable steps --steps; must be larger or equal to 1")
+		return nil, usageError(fs, "Provide suitable steps --steps; must be larger or equal to 1")
 	}
 	return &CommandLineParameters{
 		ArgString:        argstring,
 		Silent:           *silent,
+		Explain:          *explain,
+		AnalyticsColumns: *analyticscols,
 		Popsize:          *popsize,
 		Genome:           *genome,
 		Cluster:          *cluster,
 		RefRegion:        *refregion,
 		RecRate:          *rr,
 		BasePop:          *basepop,
+		BasePopVCF:       *basepopvcf,
+		BasePopResample:  *basepopresample,
+		BasePopSites:     *basepopsites,
+		BasePopSpectrum:  *basepopsfs,
+		BasePopCluster:   *basepopcluster,
+		StopWhen:         *stopwhen,
+		StopExtra:        *stopextra,
 		U:                *transrate,
 		UC:               *transrateResidual,
 		X:                *x,
@@ -132,5 +292,65 @@ func ParseCommandLine() *CommandLineParameters {
 		FileTally:        *fileTally,
 		FileSFS:          *fileSFS,
 		Generations:      *generations,
-		SampleID:         *sampleid} //TODO implement as output
+		DysU:             *dysu,
+		DysSterility:     *dyssterility,
+		PTrigger:         *ptrigger,
+		PParaEstablish:   *pparaestablish,
+		PParaMaintain:    *pparamaintain,
+		PParaLoss:        *pparaloss,
+		PirnaQuant:       *pirnaquant,
+		PirnaDeposition:  *pirnadeposition,
+		PirnaProduction:  *pirnaproduction,
+		PirnaSaturation:  *pirnasaturation,
+		ZygoticEfficacy:  *zygeff,
+		FilePirna:        *filePirna,
+		FileAge:          *fileAge,
+		PirnaModel:       *pirnamodel,
+		FileTS:           *fileTS,
+		TSSimplify:       *tssimplify,
+		FileVCF:          *fileVCF,
+		VCFSample:        *vcfsample,
+		FilePoolseq:      *filePoolseq,
+		PoolFlies:        *poolflies,
+		PoolCoverage:     *poolcov,
+		PoolDispersion:   *pooldisp,
+		FileStats:        *fileStats,
+		FileLD:           *fileLD,
+		FileSummary:      *fileSummary,
+		FileSumstats:     *fileSumstats,
+		FileAggregate:    *fileAggregate,
+		AggregateFailed:  *aggregateFailed,
+		AggregateQuant:   *aggregateQuant,
+		LDMinFreq:        *ldminfreq,
+		LDMaxDistance:    *ldmaxdist,
+		LDBinSize:        *ldbin,
+		Format:           *format,
+		FileMain:         *fileMain,
+		FileInfo:         *fileInfo,
+		SampleID:         *sampleid}, nil //TODO implement as output
+}
+
+/*
+The number of base populations provided; --basepop, --basepop-vcf and the frequency spectrum (--basepop-sites, --basepop-cluster)
+*/
+func countBasePopSources(basepop string, basepopvcf string, basepopsites int64, basepopcluster string) int {
+	count := 0
+	if basepop != "" {
+		count++
+	}
+	if basepopvcf != "" {
+		count++
+	}
+	if basepopsites != 0 || basepopcluster != "" {
+		count++
+	}
+	return count
+}
+
+/*
+Print the usage of the parameters and return the error about a missing or invalid parameter
+*/
+func usageError(fs *flag.FlagSet, message string) error {
+	fs.Usage()
+	return errors.New(message)
 }
@@ -1,8 +1,11 @@
 package cmdparser
 
 import (
+	"bufio"
 	"fmt"
 	"invade/util"
+	"math"
+	"os"
 	"strconv"
 	"strings"
 )
@@ -42,6 +45,92 @@ func ParseRegions(s string) []int64 {
 	return toret
 }
 
+/*
+Parse the genome definition into chromosome sizes and chromosome names; three definitions are supported
+i) the chromosome sizes, e.g. 'MB:2,3,1,5'; the chromosomes are numbered (names are nil)
+ii) named chromosomes with sizes, e.g. '2L:23.5Mb,2R:25.3Mb,X:23.5Mb'; units are bp (default), kb or Mb
+iii) a FASTA index file (ending with .fai); the first two columns are the name and the size of the chromosomes
+*/
+func ParseGenome(s string) ([]int64, []string) {
+	if s == "" {
+		return nil, nil
+	}
+	if strings.HasSuffix(s, ".fai") {
+		return parseFastaIndex(s)
+	}
+	tmp := strings.Split(s, ",")
+	if first := strings.Split(tmp[0], ":"); len(first) == 1 || isUnit(first[0]) {
+		return ParseRegions(s), nil
+	}
+	sizes := make([]int64, 0, len(tmp))
+	names := make([]string, 0, len(tmp))
+	for _, entry := range tmp {
+		ns := strings.Split(strings.TrimSpace(entry), ":")
+		if len(ns) != 2 || ns[0] == "" {
+			panic(fmt.Sprintf("invalid chromosome definition %s; must be name:size, e.g. 2L:23.5Mb", entry))
+		}
+		names = append(names, ns[0])
+		sizes = append(sizes, parseSize(ns[1]))
+	}
+	return sizes, names
+}
+
+/*
+Parse the chromosome names and sizes from a FASTA index file (.fai)
+*/
+func parseFastaIndex(file string) ([]int64, []string) {
+	readFile, err := os.Open(file)
+	if err != nil {
+		panic(err)
+	}
+	defer readFile.Close()
+	sizes := []int64{}
+	names := []string{}
+	fileScanner := bufio.NewScanner(readFile)
+	for fileScanner.Scan() {
+		line := strings.TrimSpace(fileScanner.Text())
+		if line == "" {
+			continue
+		}
+		tmp := strings.Split(line, "\t")
+		if len(tmp) < 2 {
+			panic(fmt.Sprintf("invalid entry in FASTA index %s", line))
+		}
+		size, err := strconv.ParseInt(tmp[1], 10, 64)
+		if err != nil || size < 1 {
+			panic(fmt.Sprintf("invalid chromosome size in FASTA index %s", line))
+		}
+		names = append(names, tmp[0])
+		sizes = append(sizes, size)
+	}
+	return sizes, names
+}
+
+/*
+Parse a size with an optional unit, e.g. 23.5Mb, 100kb, 5000bp or 5000
+*/
+func parseSize(s string) int64 {
+	ls := strings.ToLower(strings.TrimSpace(s))
+	var multiplier float64 = 1
+	for _, unit := range []string{"bp", "kb", "mb"} {
+		if strings.HasSuffix(ls, unit) {
+			multiplier = float64(getMultiplier(unit))
+			ls = strings.TrimSuffix(ls, unit)
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(ls, 64)
+	if err != nil || v <= 0.0 {
+		panic(fmt.Sprintf("invalid size %s", s))
+	}
+	return int64(math.Round(v * multiplier))
+}
+
+func isUnit(s string) bool {
+	ls := strings.ToLower(s)
+	return ls == "bp" || ls == "kb" || ls == "mb"
+}
+
 /*
 translate bp, kb or mb into 1, 1000, 1000000 respecitvely
 */
@@ -0,0 +1,202 @@
+package cmdparser
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+/*
+A main table of a simulation; the names of the columns and the rows, without the '|' separator columns of the legacy format;
+the sample IDs of the legacy format are named sample1, sample2, .. like in the other formats
+*/
+type MainTable struct {
+	Names []string
+	Rows  [][]string
+}
+
+/*
+Read a main table in any of the formats legacy, tsv, csv or jsonl; the format is detected from the first line
+*/
+func ParseMainTable(file string) *MainTable {
+	readFile, err := os.Open(file)
+	if err != nil {
+		panic(err)
+	}
+	defer readFile.Close()
+	var reader io.Reader = readFile
+	if strings.HasSuffix(file, ".gz") {
+		gz, err := gzip.NewReader(readFile)
+		if err != nil {
+			panic(err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+	// the log messages are skipped, e.g. of a main table written to stdout without --silent
+	lines := []string{}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); !strings.HasPrefix(line, "Invade: ") {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		panic(fmt.Sprintf("Invalid main table %s; the file is empty", file))
+	}
+	content := strings.NewReader(strings.Join(lines, "\n"))
+	switch {
+	case strings.HasPrefix(lines[0], "#"):
+		return parseLegacyTable(content, file)
+	case strings.HasPrefix(lines[0], "{"):
+		return parseJSONLTable(content, file)
+	case !strings.Contains(lines[0], "\t") && strings.Contains(lines[0], ","):
+		return parseCSVTable(content, file)
+	}
+	return parseTSVTable(content, file)
+}
+
+/*
+The legacy format; '# args', '# version' and '# analytics' lines, a '# ' prefixed header with the separator columns and rows with a trailing tab
+*/
+func parseLegacyTable(reader io.Reader, file string) *MainTable {
+	t := &MainTable{}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || (strings.HasPrefix(line, "#") && !strings.Contains(line, "\t")) {
+			continue // e.g. '# args', '# version' and '# analytics'
+		}
+		if strings.HasPrefix(line, "# ") {
+			t.Names = []string{}
+			for _, n := range removeSeparators(strings.Split(strings.TrimPrefix(line, "# "), "\t")) {
+				if n != "sampleids" {
+					t.Names = append(t.Names, n)
+				}
+			}
+			continue
+		}
+		if t.Names == nil {
+			panic(fmt.Sprintf("Invalid main table %s; no header", file))
+		}
+		values := removeSeparators(strings.Split(strings.TrimRight(line, "\t"), "\t"))
+		if len(values) < len(t.Names) {
+			panic(fmt.Sprintf("Invalid main table %s; expected %d columns, got %d in line '%s'", file, len(t.Names), len(values), line))
+		}
+		// sample IDs
+		for k, columns := 1, len(t.Names); columns+k <= len(values); k++ {
+			t.Names = append(t.Names, fmt.Sprintf("sample%d", k))
+		}
+		t.Rows = append(t.Rows, values)
+	}
+	return t
+}
+
+func removeSeparators(fields []string) []string {
+	toret := []string{}
+	for _, f := range fields {
+		if f != "|" {
+			toret = append(toret, f)
+		}
+	}
+	return toret
+}
+
+func parseTSVTable(reader io.Reader, file string) *MainTable {
+	t := &MainTable{}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if t.Names == nil {
+			t.Names = fields
+			continue
+		}
+		t.addRow(fields, file)
+	}
+	return t
+}
+
+func parseCSVTable(reader io.Reader, file string) *MainTable {
+	t := &MainTable{}
+	records, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		panic(fmt.Sprintf("Invalid main table %s; %v", file, err))
+	}
+	for _, fields := range records {
+		if t.Names == nil {
+			t.Names = fields
+			continue
+		}
+		t.addRow(fields, file)
+	}
+	return t
+}
+
+/*
+One JSON object per line; the columns are in the order of the keys of the first object
+*/
+func parseJSONLTable(reader io.Reader, file string) *MainTable {
+	t := &MainTable{}
+	dec := json.NewDecoder(reader)
+	dec.UseNumber()
+	for dec.More() {
+		names, values := decodeJSONRecord(dec, file)
+		if t.Names == nil {
+			t.Names = names
+		}
+		t.addRow(values, file)
+	}
+	return t
+}
+
+func decodeJSONRecord(dec *json.Decoder, file string) ([]string, []string) {
+	names, values := []string{}, []string{}
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		panic(fmt.Sprintf("Invalid main table %s; expected a JSON object per line", file))
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			panic(fmt.Sprintf("Invalid main table %s; %v", file, err))
+		}
+		val, err := dec.Token()
+		if err != nil {
+			panic(fmt.Sprintf("Invalid main table %s; %v", file, err))
+		}
+		names = append(names, fmt.Sprintf("%v", key))
+		values = append(values, fmt.Sprintf("%v", val))
+	}
+	dec.Token() // closing '}'
+	return names, values
+}
+
+func (t *MainTable) addRow(fields []string, file string) {
+	if len(fields) != len(t.Names) {
+		panic(fmt.Sprintf("Invalid main table %s; expected %d columns, got %d", file, len(t.Names), len(fields)))
+	}
+	t.Rows = append(t.Rows, fields)
+}
+
+/*
+The index of a column; -1 if the table has no such column
+*/
+func (t *MainTable) GetColumn(name string) int {
+	for i, n := range t.Names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -5,6 +5,9 @@ import (
 	"invade/env"
 	"invade/fly"
 	"invade/util"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -143,3 +146,350 @@ func TestRecurrentRegion(t *testing.T) {
 	}
 
 }
+
+func TestParseNamedGenome(t *testing.T) {
+	fai := filepath.Join(t.TempDir(), "genome.fa.fai")
+	os.WriteFile(fai, []byte("2L\t23513712\t5\t80\t81\nX\t23542271\t23807115\t80\t81\n"), 0644)
+	var tests = []struct {
+		toparse   string
+		wantsizes []int64
+		wantnames []string
+	}{
+		{toparse: "mb:2,3", wantsizes: []int64{2000000, 3000000}, wantnames: nil},
+		{toparse: "2,3", wantsizes: []int64{2, 3}, wantnames: nil},
+		{toparse: "2L:23.5Mb,2R:25.3Mb,X:100kb", wantsizes: []int64{23500000, 25300000, 100000}, wantnames: []string{"2L", "2R", "X"}},
+		{toparse: "chr1:5000,chr2:200bp", wantsizes: []int64{5000, 200}, wantnames: []string{"chr1", "chr2"}},
+		{toparse: fai, wantsizes: []int64{23513712, 23542271}, wantnames: []string{"2L", "X"}},
+	}
+	for _, test := range tests {
+		sizes, names := ParseGenome(test.toparse)
+		if len(sizes) != len(test.wantsizes) || len(names) != len(test.wantnames) {
+			t.Errorf("Incorrect genome for %s; got %v %v", test.toparse, sizes, names)
+			continue
+		}
+		for i := range sizes {
+			if sizes[i] != test.wantsizes[i] {
+				t.Errorf("Incorrect chromosome size for %s; got %v", test.toparse, sizes)
+			}
+		}
+		for i := range names {
+			if names[i] != test.wantnames[i] {
+				t.Errorf("Incorrect chromosome names for %s; got %v", test.toparse, names)
+			}
+		}
+	}
+}
+
+func TestParseBasePopLineV2(t *testing.T) {
+	env.SetupEnvironment([]int64{1000, 500}, []int64{0, 0}, []int64{0, 0}, []bool{}, []bool{}, []float64{1, 1}, 0.1, 1000.0)
+	env.SetupChromosomeNames([]string{"2L", "X"})
+	var tests = []struct {
+		line     string
+		wantnil  bool
+		wanterr  bool
+		wanthap1 []int64
+		wanthap2 []int64
+		wantdups int
+	}{
+		{line: "", wantnil: true},
+		{line: "   # a comment", wantnil: true},
+		{line: "10 R 0; 2L:1,1000 X:1; X:500 # inline comment", wanthap1: []int64{0, 999, 1000}, wanthap2: []int64{1499}},
+		{line: "10 f 3;;", wanthap1: []int64{}, wanthap2: []int64{}},
+		{line: "10 M 0; 2L:5,5; 2L:5", wanthap1: []int64{4}, wanthap2: []int64{4}, wantdups: 1},
+		{line: "10 R 0; 2L:0;", wanterr: true},  // positions are 1-based
+		{line: "10 R 0; X:501;", wanterr: true}, // outside of chromosome
+		{line: "10 R 0; 3R:5;", wanterr: true},  // unknown chromosome
+		{line: "10 R 0; 2L:a;", wanterr: true},  // invalid position
+		{line: "10 R 0; 2L:5", wanterr: true},   // missing field
+		{line: "10 Q 0;;", wanterr: true},       // invalid sex
+		{line: "0 R 0;;", wanterr: true},        // invalid count
+		{line: "10 R 0; 500;", wanterr: true},   // linear coordinates are not allowed
+	}
+	for _, test := range tests {
+		got, err := parseBasePopLineV2(test.line)
+		if test.wanterr {
+			if err == nil {
+				t.Errorf("Expected an error for line '%s'", test.line)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for line '%s': %s", test.line, err.Error())
+			continue
+		}
+		if test.wantnil {
+			if got != nil {
+				t.Errorf("Expected no entry for line '%s'", test.line)
+			}
+			continue
+		}
+		if len(got.hap1) != len(test.wanthap1) || len(got.hap2) != len(test.wanthap2) || len(got.duplicates) != test.wantdups {
+			t.Errorf("Incorrect entry for line '%s'; got %+v", test.line, got)
+			continue
+		}
+		for i := range got.hap1 {
+			if got.hap1[i] != test.wanthap1[i] {
+				t.Errorf("Incorrect haplotype 1 for line '%s'; got %v", test.line, got.hap1)
+			}
+		}
+		for i := range got.hap2 {
+			if got.hap2[i] != test.wanthap2[i] {
+				t.Errorf("Incorrect haplotype 2 for line '%s'; got %v", test.line, got.hap2)
+			}
+		}
+	}
+}
+
+func TestLoadPopulationV2(t *testing.T) {
+	env.SetupEnvironment([]int64{1000, 500}, []int64{0, 0}, []int64{0, 0}, []bool{}, []bool{}, []float64{1, 1}, 0.1, 1000.0)
+	env.SetupChromosomeNames([]string{"2L", "X"})
+	fly.SetupFitness(0, 0, true, false)
+	file := filepath.Join(t.TempDir(), "basepop.txt")
+	os.WriteFile(file, []byte("\n"+BASEPOPV2HEADER+"\n# two flies\n\n1 M 0; 2L:10; X:20\n1 F 0;;\n"), 0644)
+	pop := ParseBasePop(file, 2)
+	if pop.Size() != 2 {
+		t.Fatalf("Incorrect population size; got %d", pop.Size())
+	}
+	f := pop.Flies[0]
+	if f.Sex != fly.MALE || len(f.Hap1) != 1 || f.Hap1[0] != 9 || len(f.Hap2) != 1 || f.Hap2[0] != 1019 {
+		t.Errorf("Incorrect fly; got %v %v", f.Hap1, f.Hap2)
+	}
+
+	// the legacy format keeps its mapping of the haplotypes; the first listed haplotype is the female gamete (Hap2)
+	os.WriteFile(file, []byte("1 M 0; 9; 1019\n1 F 0;;\n"), 0644)
+	f = ParseBasePop(file, 2).Flies[0]
+	if len(f.Hap1) != 1 || f.Hap1[0] != 1019 || len(f.Hap2) != 1 || f.Hap2[0] != 9 {
+		t.Errorf("Incorrect fly of the legacy format; got %v %v", f.Hap1, f.Hap2)
+	}
+}
+
+func TestParseBasePopVCF(t *testing.T) {
+	env.SetupEnvironment([]int64{1000, 500}, []int64{0, 0}, []int64{0, 0}, []bool{}, []bool{}, []float64{1, 1}, 0.1, 1000.0)
+	env.SetupChromosomeNames([]string{"2L", "X"})
+	fly.SetupFitness(0, 0, true, false)
+	file := filepath.Join(t.TempDir(), "basepop.vcf")
+	vcf := "##fileformat=VCFv4.2\n" +
+		"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\ts1\ts2\n" +
+		"2L\t10\t.\tN\t<INS:ME>\t.\tPASS\t.\tGT\t1|0\t0|0\n" +
+		"X\t20\t.\tN\t<INS:ME>\t.\tPASS\t.\tDP:GT\t5:1|1\t3:.|1\n"
+	os.WriteFile(file, []byte(vcf), 0644)
+	pop := ParseBasePopVCF(file, 2, false)
+	var tests = []struct {
+		hap1 []int64
+		hap2 []int64
+	}{
+		{hap1: []int64{9, 1019}, hap2: []int64{1019}},
+		{hap1: []int64{}, hap2: []int64{1019}},
+	}
+	for i, test := range tests {
+		f := pop.Flies[i]
+		if len(f.Hap1) != len(test.hap1) || len(f.Hap2) != len(test.hap2) {
+			t.Errorf("Incorrect haplotypes of sample %d; got %v %v", i+1, f.Hap1, f.Hap2)
+			continue
+		}
+		for k := range f.Hap1 {
+			if f.Hap1[k] != test.hap1[k] {
+				t.Errorf("Incorrect first haplotype of sample %d; got %v", i+1, f.Hap1)
+			}
+		}
+		for k := range f.Hap2 {
+			if f.Hap2[k] != test.hap2[k] {
+				t.Errorf("Incorrect second haplotype of sample %d; got %v", i+1, f.Hap2)
+			}
+		}
+	}
+	resampled := ParseBasePopVCF(file, 10, true)
+	if resampled.Size() != 10 {
+		t.Errorf("Incorrect size of the resampled population; got %d", resampled.Size())
+	}
+}
+
+func TestParseBasePopSpectrum(t *testing.T) {
+	env.SetupEnvironment([]int64{10000, 10000}, []int64{100, 100}, []int64{0, 0}, []bool{}, []bool{}, []float64{1, 1}, 0.1, 1000.0)
+	fly.SetupFitness(0, 0, true, false)
+	var tests = []struct {
+		sites    int64
+		spectrum string
+		cluster  string
+		want     map[int64]int64 // number of sites with a given number of copies
+	}{
+		{sites: 10, spectrum: "fixed:0.5", cluster: "", want: map[int64]int64{50: 10}},
+		{sites: 4, spectrum: "fixed:0.01,1.0", cluster: "", want: map[int64]int64{1: 2, 100: 2}},
+		{sites: 0, spectrum: "neutral", cluster: "0.2,0.2,0.001", want: map[int64]int64{20: 2, 1: 1}},
+	}
+	for _, test := range tests {
+		pop := ParseBasePopSpectrum(test.sites, test.spectrum, test.cluster, 50)
+		copies := make(map[int64]int64)
+		for _, f := range pop.Flies {
+			for _, s := range f.Hap1 {
+				copies[s]++
+			}
+			for _, s := range f.Hap2 {
+				copies[s]++
+			}
+		}
+		got := make(map[int64]int64)
+		for site, c := range copies {
+			got[c]++
+			if test.cluster != "" && !env.IsClusterInsertion(site) {
+				t.Errorf("Insertion %d of %s is not a cluster insertion", site, test.cluster)
+			}
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("Incorrect frequency spectrum for %s; want %v, got %v", test.spectrum, test.want, got)
+		}
+		for c, n := range test.want {
+			if got[c] != n {
+				t.Errorf("Incorrect frequency spectrum for %s; want %v, got %v", test.spectrum, test.want, got)
+			}
+		}
+	}
+
+	// random spectra only yield segregating sites
+	for _, spectrum := range []string{"uniform", "neutral"} {
+		pop := ParseBasePopSpectrum(200, spectrum, "", 5)
+		copies := make(map[int64]int64)
+		for _, f := range pop.Flies {
+			for _, s := range append(append([]int64{}, f.Hap1...), f.Hap2...) {
+				copies[s]++
+			}
+		}
+		if len(copies) != 200 {
+			t.Errorf("Incorrect number of sites for %s; got %d", spectrum, len(copies))
+		}
+		for _, c := range copies {
+			if c < 1 || c > 9 {
+				t.Errorf("Site is not segregating for %s; %d copies", spectrum, c)
+			}
+		}
+	}
+}
+
+func TestParseBasePopSpectrumFullGenome(t *testing.T) {
+	// all positions of the genome are used; the cluster insertions must not wait for positions occupied by the random sites
+	env.SetupEnvironment([]int64{20}, []int64{10}, []int64{0}, []bool{}, []bool{}, []float64{1}, 0.1, 1000.0)
+	fly.SetupFitness(0, 0, true, false)
+	pop := ParseBasePopSpectrum(10, "fixed:0.5", "0.5,0.5,0.5,0.5,0.5,0.5,0.5,0.5,0.5,0.5", 10)
+	sites := make(map[int64]bool)
+	for _, f := range pop.Flies {
+		for _, s := range append(append([]int64{}, f.Hap1...), f.Hap2...) {
+			sites[s] = true
+		}
+	}
+	if len(sites) != 20 {
+		t.Errorf("Incorrect number of sites; got %d, want 20", len(sites))
+	}
+}
+
+func TestCountBasePopSources(t *testing.T) {
+	var tests = []struct {
+		basepop string
+		vcf     string
+		sites   int64
+		cluster string
+		want    int
+	}{
+		{basepop: "100", want: 1},
+		{vcf: "basepop.vcf", want: 1},
+		{sites: 100, cluster: "0.1", want: 1},
+		{cluster: "0.1", want: 1},
+		{basepop: "100", cluster: "0.1", want: 2},
+		{basepop: "100", vcf: "basepop.vcf", want: 2},
+		{vcf: "basepop.vcf", sites: 10, want: 2},
+		{basepop: "100", vcf: "basepop.vcf", sites: 10, want: 3},
+	}
+	for _, test := range tests {
+		if got := countBasePopSources(test.basepop, test.vcf, test.sites, test.cluster); got != test.want {
+			t.Errorf("Incorrect number of base populations for %+v; got %d", test, got)
+		}
+	}
+}
+
+func TestNeutralCumulative(t *testing.T) {
+	cum := getNeutralCumulative(4)
+	want := []float64{1.0, 1.5, 1.5 + 1.0/3.0}
+	if len(cum) != len(want) {
+		t.Fatalf("Incorrect neutral spectrum; want %v, got %v", want, cum)
+	}
+	for i := range want {
+		if cum[i]-want[i] > 1e-9 || want[i]-cum[i] > 1e-9 {
+			t.Errorf("Incorrect neutral spectrum; want %v, got %v", want, cum)
+		}
+	}
+}
+
+func TestSplitSubcommand(t *testing.T) {
+	var tests = []struct {
+		args    []string
+		command string
+		rest    int
+	}{
+		{args: []string{}, command: SUBHELP, rest: 0},
+		{args: []string{"--help"}, command: SUBHELP, rest: 0},
+		{args: []string{"--N", "100", "--gen", "10"}, command: SUBSIMULATE, rest: 4}, // backward compatible
+		{args: []string{"simulate", "--N", "100"}, command: SUBSIMULATE, rest: 2},
+		{args: []string{"convert", "--in", "main.txt"}, command: SUBCONVERT, rest: 2},
+	}
+	for _, test := range tests {
+		command, rest := SplitSubcommand(test.args)
+		if command != test.command || len(rest) != test.rest {
+			t.Errorf("Incorrect subcommand of %v; want %s with %d arguments, got %s with %v", test.args, test.command, test.rest, command, rest)
+		}
+	}
+}
+
+func TestParseMainTable(t *testing.T) {
+	var tests = []struct {
+		content string
+		names   []string
+		row     []string
+	}{
+		{content: "# args: --N 10\n# version 0.2.3, seed: 1\n# rep\tgen\t|\tavtes\torifreq\t|\tsampleids\n1\t0\t|\t0.50\t1:0.5,2:0.5\t|\ta\tb\t\n",
+			names: []string{"rep", "gen", "avtes", "orifreq", "sample1", "sample2"}, row: []string{"1", "0", "0.50", "1:0.5,2:0.5", "a", "b"}},
+		{content: "rep\tgen\tavtes\torifreq\n1\t0\t0.50\t1:0.5,2:0.5\n",
+			names: []string{"rep", "gen", "avtes", "orifreq"}, row: []string{"1", "0", "0.50", "1:0.5,2:0.5"}},
+		{content: "rep,gen,avtes,orifreq\n1,0,0.50,\"1:0.5,2:0.5\"\n",
+			names: []string{"rep", "gen", "avtes", "orifreq"}, row: []string{"1", "0", "0.50", "1:0.5,2:0.5"}},
+		{content: "{\"rep\":1,\"gen\":0,\"avtes\":0.50,\"orifreq\":\"1:0.5,2:0.5\"}\n",
+			names: []string{"rep", "gen", "avtes", "orifreq"}, row: []string{"1", "0", "0.50", "1:0.5,2:0.5"}},
+	}
+	for i, test := range tests {
+		file := filepath.Join(t.TempDir(), "main.txt")
+		os.WriteFile(file, []byte(test.content), 0644)
+		table := ParseMainTable(file)
+		if len(table.Rows) != 1 || strings.Join(table.Names, " ") != strings.Join(test.names, " ") || strings.Join(table.Rows[0], " ") != strings.Join(test.row, " ") {
+			t.Errorf("Incorrect main table %d; got %v %v", i, table.Names, table.Rows)
+		}
+	}
+}
+
+func TestParseCommandLineErrors(t *testing.T) {
+	valid := []string{"--N", "100", "--gen", "10", "--genome", "mb:1", "--basepop", "10"}
+	var tests = []struct {
+		args    []string
+		wanterr string
+	}{
+		{args: valid},
+		{args: []string{"--gen", "10", "--genome", "mb:1", "--basepop", "10"}, wanterr: "--N"},
+		{args: []string{"--N", "100", "--genome", "mb:1", "--basepop", "10"}, wanterr: "--gen"},
+		{args: append([]string{"--u", "-0.1"}, valid...), wanterr: "--u"},
+		{args: append([]string{"--basepop-vcf", "base.vcf"}, valid...), wanterr: "mutually exclusive"},
+		{args: append([]string{"--format", "xml"}, valid...), wanterr: "--format"},
+	}
+	for _, test := range tests {
+		clp, err := ParseCommandLine(SUBSIMULATE, test.args)
+		if test.wanterr == "" {
+			if err != nil || clp == nil || clp.Popsize != 100 {
+				t.Errorf("Incorrect parameters of %v; got %v", test.args, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), test.wanterr) {
+			t.Errorf("Missing error about %s for %v; got %v", test.wanterr, test.args, err)
+		}
+	}
+	if err := CheckSubcommand("simulte"); err == nil {
+		t.Errorf("Unknown subcommand was not detected")
+	}
+	if _, err := ParseConvertCommandLine([]string{"--in", "main.txt", "--to", "xml"}); err == nil {
+		t.Errorf("Invalid format of the conversion was not detected")
+	}
+}
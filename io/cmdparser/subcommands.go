@@ -0,0 +1,135 @@
+package cmdparser
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	SUBSIMULATE  string = "simulate"
+	SUBVALIDATE  string = "validate"
+	SUBSUMMARIZE string = "summarize"
+	SUBCONVERT   string = "convert"
+	SUBSWEEP     string = "sweep"
+	SUBABC       string = "abc"
+	SUBHELP      string = "help"
+)
+
+/*
+A subcommand of invade, e.g. 'invade simulate --N 1000 ..'
+*/
+type Subcommand struct {
+	Name        string
+	Description string
+}
+
+var Subcommands = []Subcommand{
+	{Name: SUBSIMULATE, Description: "simulate TE invasions; the default, i.e. the parameters also work without a subcommand"},
+	{Name: SUBVALIDATE, Description: "check the genome, the piRNA clusters, the other regions and the base population without simulating (dry run)"},
+	{Name: SUBSUMMARIZE, Description: "aggregate main tables across replicates (mean, standard deviation and quantiles of each numeric column per generation)"},
+	{Name: SUBCONVERT, Description: "convert a main table between the formats legacy, tsv, csv and jsonl"},
+	{Name: SUBSWEEP, Description: "simulate grids of parameters in parallel"},
+	{Name: SUBABC, Description: "approximate Bayesian computation; rejection sampling from priors of the parameters"},
+	{Name: SUBHELP, Description: "show the help of a subcommand, e.g. 'invade help simulate'"},
+}
+
+/*
+Split the command line arguments into the subcommand and its arguments;
+for backward compatibility arguments starting with parameters (e.g. 'invade --N 1000 ..') are passed to 'simulate'
+*/
+func SplitSubcommand(args []string) (string, []string) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "-help" {
+		return SUBHELP, []string{}
+	}
+	if strings.HasPrefix(args[0], "-") {
+		return SUBSIMULATE, args
+	}
+	return args[0], args[1:]
+}
+
+/*
+Check a subcommand; an error for unknown subcommands
+*/
+func CheckSubcommand(name string) error {
+	if GetSubcommandDescription(name) == "" {
+		return fmt.Errorf("Unknown subcommand %s; see 'invade help'", name)
+	}
+	return nil
+}
+
+func GetSubcommandDescription(name string) string {
+	for _, s := range Subcommands {
+		if s.Name == name {
+			return s.Description
+		}
+	}
+	return ""
+}
+
+func PrintUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: invade <subcommand> [parameters]\n\nSubcommands:")
+	for _, s := range Subcommands {
+		fmt.Fprintf(os.Stderr, "  %-10s  %s\n", s.Name, s.Description)
+	}
+	fmt.Fprintln(os.Stderr, "\nThe parameters of a subcommand are shown with 'invade <subcommand> --help'")
+}
+
+/*
+The parameters of 'invade summarize'
+*/
+type SummarizeParameters struct {
+	Files     []string // main tables, e.g. of several runs with different --replicate-offset
+	Out       string
+	Failed    string // handling of replicates which ended early; exclude or carry
+	Quantiles string
+	Steps     int64 // the recorded generations; inferred from the main tables if 0
+	Gen       int64 // the last generation of the simulations; the last generation of the main tables if 0
+}
+
+func ParseSummarizeCommandLine(args []string) (*SummarizeParameters, error) {
+	fs := flag.NewFlagSet(SUBSUMMARIZE, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: invade %s [parameters] main1.txt [main2.txt ..]\n\n%s;\nthe main tables may be in any format (legacy, tsv, csv, jsonl) and gzip compressed\n\nParameters:\n", SUBSUMMARIZE, GetSubcommandDescription(SUBSUMMARIZE))
+		fs.PrintDefaults()
+	}
+	out := fs.String("out", "", "output file; default is stdout")
+	failed := fs.String("failed", "exclude", "replicates which ended early (failed or stopped) are either excluded from later generations ('exclude') or contribute their last values ('carry')")
+	quantiles := fs.String("quantiles", "0.025,0.5,0.975", "the quantiles across replicates")
+	steps := fs.Int64("steps", 0, "the recorded generations of the simulations (--steps); failed populations at other generations are solely carried. Default: inferred from the main tables")
+	gen := fs.Int64("gen", 0, "the last generation of the simulations (--gen); with 'carry' the recorded generations up to it are written. Default: the last generation of the main tables")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		return nil, usageError(fs, "Provide at least one main table")
+	}
+	return &SummarizeParameters{Files: fs.Args(), Out: *out, Failed: *failed, Quantiles: *quantiles, Steps: *steps, Gen: *gen}, nil
+}
+
+/*
+The parameters of 'invade convert'
+*/
+type ConvertParameters struct {
+	In     string
+	Out    string
+	Format string
+}
+
+func ParseConvertCommandLine(args []string) (*ConvertParameters, error) {
+	fs := flag.NewFlagSet(SUBCONVERT, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: invade %s --in main.txt --to tsv [--out main.tsv]\n\n%s;\nthe format of the input is detected automatically; gzip compressed if the file ends with '.gz'\n\nParameters:\n", SUBCONVERT, GetSubcommandDescription(SUBCONVERT))
+		fs.PrintDefaults()
+	}
+	in := fs.String("in", "", "mandatory; the main table")
+	to := fs.String("to", "", "mandatory; the format of the output; 'legacy', 'tsv', 'csv' or 'jsonl'")
+	out := fs.String("out", "", "output file; default is stdout")
+	fs.Parse(args)
+	if *in == "" {
+		return nil, usageError(fs, "Provide a suitable main table --in")
+	}
+	if *to != "legacy" && *to != "tsv" && *to != "csv" && *to != "jsonl" {
+		return nil, usageError(fs, "Provide a suitable format --to; must be 'legacy', 'tsv', 'csv' or 'jsonl'")
+	}
+	return &ConvertParameters{In: *in, Out: *out, Format: *to}, nil
+}
@@ -0,0 +1,28 @@
+package writer
+
+import (
+	"fmt"
+	"invade/fly"
+)
+
+var agewriter *Output
+
+func SetupAgeWriter(file string) {
+	agewriter = CreateOutput(file)
+}
+
+/*
+Write the age distribution of the insertions; for each age (in generations) the number of sites and the number of copies
+*/
+func WriteAgeEntry(p *fly.Population, replicate int64, generation int64) {
+	for _, ac := range p.GetAgeSpectrum() {
+		printline := fmt.Sprintf("%d\t%d\t%d\t%d\t%d", replicate, generation, ac.Age, ac.Sites, ac.Copies)
+		agewriter.WriteString(printline + "\n")
+	}
+}
+
+func CloseAgeWriter() {
+	if agewriter != nil {
+		agewriter.Close()
+	}
+}
@@ -0,0 +1,32 @@
+package writer
+
+import (
+	"strings"
+)
+
+var aggregatewriter *Output
+
+/*
+Setup the writer of the values aggregated across replicates; the file starts with a header line; stdout if no file is provided
+*/
+func SetupAggregateWriter(file string, header []string) {
+	if file == "" {
+		aggregatewriter = StdoutOutput()
+	} else {
+		aggregatewriter = CreateOutput(file)
+	}
+	aggregatewriter.WriteString(strings.Join(header, "\t") + "\n")
+}
+
+/*
+Write the aggregated values of a single column of the main table for a generation, e.g. mean, standard deviation and quantiles
+*/
+func WriteAggregateEntry(values []string) {
+	aggregatewriter.WriteString(strings.Join(values, "\t") + "\n")
+}
+
+func CloseAggregateWriter() {
+	if aggregatewriter != nil {
+		aggregatewriter.Close()
+	}
+}
@@ -3,22 +3,30 @@ package writer
 import (
 	"fmt"
 	"invade/fly"
-	"os"
+	"invade/ld"
 )
 
-var debugwriter *os.File
+var debugwriter *Output
 
 func SetupDebugWriter(file string) {
-	tmp, err := os.Create(file)
-	if err != nil {
-		panic(err)
-	}
-	debugwriter = tmp
+	debugwriter = CreateOutput(file)
 }
 
+/*
+Debugging LD; number of pairs of insertion sites and their average D and r2 (see ld.SetupLD for the settings)
+*/
 func WriteDebugEntry(p *fly.Population, replicate int64, generation int64) {
-	d := p.GetD(0, 999999) // debugging LD decay
-	printline := fmt.Sprintf("%d\t%d\t%f", replicate, generation, d)
+	pairs := ld.GetPairs(p)
+	d, r2 := 0.0, 0.0
+	for _, pl := range pairs {
+		d += pl.D
+		r2 += pl.R2
+	}
+	if len(pairs) > 0 {
+		d /= float64(len(pairs))
+		r2 /= float64(len(pairs))
+	}
+	printline := fmt.Sprintf("%d\t%d\t%d\t%f\t%f", replicate, generation, len(pairs), d, r2)
 	debugwriter.WriteString(printline + "\n")
 
 }
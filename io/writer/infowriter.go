@@ -0,0 +1,13 @@
+package writer
+
+/*
+Write the information about the simulations, i.e. the arguments, the version, the seed and the analytical predictions, one per line;
+the structured formats of the main table solely have a header line
+*/
+func WriteInfoFile(file string, lines []string) {
+	out := CreateOutput(file)
+	defer out.Close()
+	for _, line := range lines {
+		out.WriteString(line + "\n")
+	}
+}
@@ -0,0 +1,30 @@
+package writer
+
+import (
+	"fmt"
+	"invade/fly"
+	"invade/ld"
+)
+
+var ldwriter *Output
+
+func SetupLDWriter(file string) {
+	ldwriter = CreateOutput(file)
+}
+
+/*
+Write the LD decay curve; for each combination of insertion categories and distance bin the number of pairs, the average D, |D'| and r2;
+the bins are [start, end) except the last one, which is closed and ends at the maximum distance (--ld-max-dist)
+*/
+func WriteLDEntry(p *fly.Population, replicate int64, generation int64) {
+	for _, db := range ld.GetDecay(p) {
+		printline := fmt.Sprintf("%d\t%d\t%s\t%d\t%d\t%d\t%f\t%f\t%f", replicate, generation, db.Pair, db.Start, db.End, db.Pairs, db.D, db.AbsDPrime, db.R2)
+		ldwriter.WriteString(printline + "\n")
+	}
+}
+
+func CloseLDWriter() {
+	if ldwriter != nil {
+		ldwriter.Close()
+	}
+}
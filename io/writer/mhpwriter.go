@@ -4,25 +4,31 @@ import (
 	"fmt"
 	"invade/env"
 	"invade/fly"
-	"os"
 )
 
-var mhpwriter *os.File
+var mhpwriter *Output
 
 func SetupMHPWriter(file string) {
-	tmp, err := os.Create(file)
-	if err != nil {
-		panic(err)
-	}
-	mhpwriter = tmp
+	mhpwriter = CreateOutput(file)
 }
 
+/*
+Write the position and population frequency of each insertion;
+also the age of the insertion (in generations) and the number of the fly in which the insertion arose (0 for the base population);
+for sites with several origins (recurrent insertions) the age and the fly of the oldest copy
+*/
 func WriteMHPEntry(p *fly.Population, replicate int64, generation int64) {
 	insfreq := p.GetMHPPopulationFrequency()
+	origins := p.GetInsertionOrigins()
 	for pos, freq := range insfreq {
 		score := env.ScoreInsertion(pos)
 		chrm, chrpos := env.TranslateCoordinates(pos)
-		printline := fmt.Sprintf("%d\t%d\t%d\t%d\t%s\t%f", replicate, generation, chrm, chrpos, score, freq)
+		origin, ok := origins[pos]
+		age := int64(-1) // not tracked
+		if ok {
+			age = p.GetInsertionAge(origin)
+		}
+		printline := fmt.Sprintf("%d\t%d\t%s\t%d\t%s\t%f\t%d\t%d", replicate, generation, env.GetChromosomeName(chrm), chrpos, score, freq, age, origin.Fly)
 		mhpwriter.WriteString(printline + "\n")
 	}
 
@@ -0,0 +1,223 @@
+package writer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"invade/util"
+	"os"
+	"strings"
+	"sync"
+)
+
+/*
+A buffered output; compressed with gzip if the name of the file ends with '.gz'.
+Output must be closed to flush the buffer (see CloseAllOutputs).
+The first error of writing, flushing or closing is kept; later writes are skipped and the error is returned by Close (see also GetOutputError)
+*/
+type Output struct {
+	lock   sync.Mutex
+	name   string
+	file   *os.File
+	gz     *gzip.Writer
+	buf    *bufio.Writer
+	err    error
+	closed bool
+	stdout bool // the standard output is never closed
+}
+
+var openOutputs = make(map[*Output]bool) // outputs are removed when closed
+var openLock sync.Mutex
+var outputError error // the first error of any output
+
+/*
+Create a novel output file; gzip compressed if the file name ends with '.gz'
+*/
+func CreateOutput(file string) *Output {
+	tmp, err := os.Create(file)
+	if err != nil {
+		panic(err)
+	}
+	o := &Output{name: file, file: tmp}
+	if strings.HasSuffix(file, ".gz") {
+		o.gz = gzip.NewWriter(tmp)
+		o.buf = bufio.NewWriter(o.gz)
+	} else {
+		o.buf = bufio.NewWriter(tmp)
+	}
+	registerOutput(o)
+	return o
+}
+
+/*
+Append to an output file (created if it does not exist); a gzip compressed file is continued with a novel gzip member
+*/
+func AppendOutput(file string) *Output {
+	tmp, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		panic(err)
+	}
+	o := &Output{name: file, file: tmp}
+	if strings.HasSuffix(file, ".gz") {
+		o.gz = gzip.NewWriter(tmp)
+		o.buf = bufio.NewWriter(o.gz)
+	} else {
+		o.buf = bufio.NewWriter(tmp)
+	}
+	registerOutput(o)
+	return o
+}
+
+/*
+Buffered standard output; flushed when closed and before each message of the log (if the log is written to stdout),
+thus the log messages and the output keep their order
+*/
+func StdoutOutput() *Output {
+	o := &Output{name: "stdout", file: os.Stdout, buf: bufio.NewWriter(os.Stdout), stdout: true}
+	registerOutput(o)
+	if util.InvadeLogger.Writer() == os.Stdout {
+		util.InvadeLogger.SetOutput(stdoutLog{})
+	}
+	return o
+}
+
+/*
+The log written to stdout; the buffered standard outputs are flushed before each message
+*/
+type stdoutLog struct{}
+
+func (stdoutLog) Write(b []byte) (int, error) {
+	openLock.Lock()
+	outputs := make([]*Output, 0, len(openOutputs))
+	for o := range openOutputs {
+		if o.stdout {
+			outputs = append(outputs, o)
+		}
+	}
+	openLock.Unlock()
+	for _, o := range outputs {
+		o.Flush()
+	}
+	return os.Stdout.Write(b)
+}
+
+func registerOutput(o *Output) {
+	openLock.Lock()
+	defer openLock.Unlock()
+	openOutputs[o] = true
+}
+
+func deregisterOutput(o *Output) {
+	openLock.Lock()
+	defer openLock.Unlock()
+	delete(openOutputs, o)
+}
+
+/*
+Keep the first error of the output (and of all outputs); must be called with the lock of the output
+*/
+func (o *Output) setError(err error) {
+	if err == nil || o.err != nil {
+		return
+	}
+	o.err = fmt.Errorf("Could not write output %s: %w", o.name, err)
+	openLock.Lock()
+	defer openLock.Unlock()
+	if outputError == nil {
+		outputError = o.err
+	}
+}
+
+func (o *Output) WriteString(s string) (int, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.closed {
+		return 0, os.ErrClosed
+	}
+	if o.err != nil {
+		return 0, o.err
+	}
+	n, err := o.buf.WriteString(s)
+	o.setError(err)
+	return n, o.err
+}
+
+func (o *Output) Write(b []byte) (int, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.closed {
+		return 0, os.ErrClosed
+	}
+	if o.err != nil {
+		return 0, o.err
+	}
+	n, err := o.buf.Write(b)
+	o.setError(err)
+	return n, o.err
+}
+
+/*
+Flush the buffer (and the gzip stream) without closing the file; e.g. to keep the completed results when a run is interrupted
+*/
+func (o *Output) Flush() {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.closed || o.err != nil {
+		return
+	}
+	o.setError(o.buf.Flush())
+	if o.gz != nil && o.err == nil {
+		o.setError(o.gz.Flush())
+	}
+}
+
+/*
+Flush the buffer (and the gzip stream) and close the file; closing twice is harmless.
+Returns the first error of the output, e.g. of a full disk or of a closed pipe
+*/
+func (o *Output) Close() error {
+	if o == nil {
+		return nil
+	}
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.closed {
+		return o.err
+	}
+	o.closed = true
+	if o.err == nil {
+		o.setError(o.buf.Flush())
+	}
+	if o.gz != nil {
+		o.setError(o.gz.Close())
+	}
+	if !o.stdout {
+		o.setError(o.file.Close())
+	}
+	deregisterOutput(o)
+	return o.err
+}
+
+/*
+Flush and close all outputs that are still open; e.g. after a panic or an interrupt
+*/
+func CloseAllOutputs() {
+	openLock.Lock()
+	outputs := make([]*Output, 0, len(openOutputs))
+	for o := range openOutputs {
+		outputs = append(outputs, o)
+	}
+	openLock.Unlock()
+	for _, o := range outputs {
+		o.Close() // removes the output from openOutputs
+	}
+}
+
+/*
+The first error of any output (e.g. a full disk or a closed pipe); nil if all outputs were written successfully
+*/
+func GetOutputError() error {
+	openLock.Lock()
+	defer openLock.Unlock()
+	return outputError
+}
@@ -0,0 +1,76 @@
+package writer
+
+import (
+	"invade/util"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutputRegistry(test *testing.T) {
+	dir := test.TempDir()
+	before := len(openOutputs)
+	closed := CreateOutput(filepath.Join(dir, "closed.txt"))
+	open := CreateOutput(filepath.Join(dir, "open.txt.gz"))
+	if len(openOutputs) != before+2 {
+		test.Errorf("Incorrect number of open outputs; got %d, want %d", len(openOutputs), before+2)
+	}
+	closed.Close()
+	if openOutputs[closed] || !openOutputs[open] {
+		test.Errorf("A closed output must be removed from the open outputs")
+	}
+	open.WriteString("invade\n")
+	CloseAllOutputs()
+	if len(openOutputs) != 0 {
+		test.Errorf("All outputs should be closed; got %d", len(openOutputs))
+	}
+	if _, err := open.WriteString("invade\n"); err != os.ErrClosed {
+		test.Errorf("Writing to a closed output must fail")
+	}
+}
+
+func TestOutputError(test *testing.T) {
+	o := CreateOutput(filepath.Join(test.TempDir(), "error.txt"))
+	o.file.Close() // e.g. a full disk or a closed pipe
+	o.WriteString(strings.Repeat("invade\n", 1000))
+	if _, err := o.WriteString(strings.Repeat("invade\n", 1000)); err == nil {
+		test.Errorf("Writing to a failed output must return the error")
+	}
+	if err := o.Close(); err == nil || !strings.Contains(err.Error(), "error.txt") {
+		test.Errorf("Closing a failed output must return the error with the name of the output; got %v", err)
+	}
+	if GetOutputError() == nil {
+		test.Errorf("The error of an output must be kept")
+	}
+	outputError = nil
+}
+
+func TestStdoutOutputLogOrder(test *testing.T) {
+	file := filepath.Join(test.TempDir(), "stdout.txt")
+	tmp, err := os.Create(file)
+	if err != nil {
+		test.Fatal(err)
+	}
+	stdout, logout, logflags := os.Stdout, util.InvadeLogger.Writer(), util.InvadeLogger.Flags()
+	defer func() {
+		os.Stdout = stdout
+		util.InvadeLogger.SetOutput(logout)
+		util.InvadeLogger.SetFlags(logflags)
+	}()
+	os.Stdout = tmp
+	util.InvadeLogger.SetOutput(os.Stdout)
+	util.InvadeLogger.SetFlags(0)
+	o := StdoutOutput()
+	o.WriteString("gen1\n")
+	util.InvadeLogger.Print("replicate 2")
+	o.WriteString("gen2\n")
+	if err := o.Close(); err != nil {
+		test.Errorf("Unexpected error of the standard output %v", err)
+	}
+	tmp.Close()
+	got, _ := os.ReadFile(file)
+	if want := "gen1\nInvade: replicate 2\ngen2\n"; string(got) != want {
+		test.Errorf("The log and the standard output must keep their order; got %q want %q", got, want)
+	}
+}
@@ -0,0 +1,43 @@
+package writer
+
+import (
+	"fmt"
+	"invade/fly"
+	"invade/util"
+)
+
+var pirnawriter *Output
+
+/*
+Setup the writer of the piRNA levels; the file starts with a header line
+*/
+func SetupPirnaWriter(file string) {
+	pirnawriter = CreateOutput(file)
+	pirnawriter.WriteString("rep\tgen\tmean\tmin\tq05\tq25\tq50\tq75\tq95\tmax\tfsilenced\tmatlevel\tzyglevel\n")
+}
+
+/*
+Write the distribution of piRNA levels in the population;
+mean, minimum, 5%, 25%, 50%, 75% and 95% quantiles, maximum, the fraction of fully silenced individuals,
+and the mean maternally deposited and zygotically produced piRNA level
+*/
+func WritePirnaEntry(p *fly.Population, replicate int64, generation int64) {
+	levels := p.GetPirnaLevels()
+	var sum float64
+	for _, l := range levels {
+		sum += l
+	}
+	mean := sum / float64(len(levels))
+	matlevel, zyglevel := p.GetAveragePirnaLevels()
+	printline := fmt.Sprintf("%d\t%d\t%f\t%f\t%f\t%f\t%f\t%f\t%f\t%f\t%f\t%f\t%f", replicate, generation, mean,
+		levels[0], util.Quantile(levels, 0.05), util.Quantile(levels, 0.25), util.Quantile(levels, 0.5),
+		util.Quantile(levels, 0.75), util.Quantile(levels, 0.95), levels[len(levels)-1], p.GetFullySilencedFrequency(),
+		matlevel, zyglevel)
+	pirnawriter.WriteString(printline + "\n")
+}
+
+func ClosePirnaWriter() {
+	if pirnawriter != nil {
+		pirnawriter.Close()
+	}
+}
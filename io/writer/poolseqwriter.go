@@ -0,0 +1,38 @@
+package writer
+
+import (
+	"fmt"
+	"invade/env"
+	"invade/fly"
+	"invade/poolseq"
+	"math"
+)
+
+var poolseqwriter *Output
+
+func SetupPoolseqWriter(file string) {
+	poolseqwriter = CreateOutput(file)
+}
+
+/*
+Write the simulated pool-seq data; for each insertion site the true population frequency, the frequency in the pool,
+the coverage, the number of supporting reads and the estimated population frequency (NA if not covered)
+*/
+func WritePoolseqEntry(p *fly.Population, replicate int64, generation int64) {
+	for _, ps := range poolseq.SequencePool(p) {
+		chrm, chrpos := env.TranslateCoordinates(ps.Site)
+		estfreq := "NA"
+		if est := ps.EstimatedFreq(); !math.IsNaN(est) {
+			estfreq = fmt.Sprintf("%f", est)
+		}
+		printline := fmt.Sprintf("%d\t%d\t%s\t%d\t%s\t%f\t%f\t%d\t%d\t%s", replicate, generation, env.GetChromosomeName(chrm), chrpos, env.ScoreInsertion(ps.Site),
+			ps.TrueFreq, ps.SampleFreq, ps.Coverage, ps.Support, estfreq)
+		poolseqwriter.WriteString(printline + "\n")
+	}
+}
+
+func ClosePoolseqWriter() {
+	if poolseqwriter != nil {
+		poolseqwriter.Close()
+	}
+}
@@ -0,0 +1,41 @@
+package writer
+
+import (
+	"fmt"
+	"invade/fly"
+	"invade/popgen"
+	"math"
+)
+
+var statswriter *Output
+
+func SetupStatsWriter(file string) {
+	statswriter = CreateOutput(file)
+}
+
+/*
+Write the population genetic summary statistics for each category of insertions;
+number of segregating sites, Watterson's theta, Tajima's theta (pi), Tajima's D and the expected heterozygosity (NA if undefined)
+*/
+func WriteStatsEntry(p *fly.Population, replicate int64, generation int64) {
+	stats := popgen.GetStatistics(p)
+	for _, cat := range popgen.Categories {
+		s := stats[cat]
+		printline := fmt.Sprintf("%d\t%d\t%s\t%d\t%f\t%f\t%s\t%s", replicate, generation, cat, s.Segregating, s.ThetaW, s.ThetaPi,
+			formatStatistic(s.TajimaD), formatStatistic(s.Heterozygosity))
+		statswriter.WriteString(printline + "\n")
+	}
+}
+
+func formatStatistic(v float64) string {
+	if math.IsNaN(v) {
+		return "NA"
+	}
+	return fmt.Sprintf("%f", v)
+}
+
+func CloseStatsWriter() {
+	if statswriter != nil {
+		statswriter.Close()
+	}
+}
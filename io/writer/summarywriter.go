@@ -0,0 +1,28 @@
+package writer
+
+import (
+	"strings"
+)
+
+var summarywriter *Output
+
+/*
+Setup the writer of the per-replicate summary; the file starts with a header line
+*/
+func SetupSummaryWriter(file string, header []string) {
+	summarywriter = CreateOutput(file)
+	summarywriter.WriteString(strings.Join(header, "\t") + "\n")
+}
+
+/*
+Write the summary of a replicate, i.e. the milestones of the invasion, as single line
+*/
+func WriteSummaryEntry(values []string) {
+	summarywriter.WriteString(strings.Join(values, "\t") + "\n")
+}
+
+func CloseSummaryWriter() {
+	if summarywriter != nil {
+		summarywriter.Close()
+	}
+}
@@ -0,0 +1,28 @@
+package writer
+
+import (
+	"strings"
+)
+
+var sumstatswriter *Output
+
+/*
+Setup the writer of the summary statistics (e.g. for approximate Bayesian computation); the file starts with a header line
+*/
+func SetupSumstatsWriter(file string, header []string) {
+	sumstatswriter = CreateOutput(file)
+	sumstatswriter.WriteString(strings.Join(header, "\t") + "\n")
+}
+
+/*
+Write the summary statistics of the last generation of a replicate
+*/
+func WriteSumstatsEntry(values []string) {
+	sumstatswriter.WriteString(strings.Join(values, "\t") + "\n")
+}
+
+func CloseSumstatsWriter() {
+	if sumstatswriter != nil {
+		sumstatswriter.Close()
+	}
+}
@@ -0,0 +1,69 @@
+package writer
+
+import (
+	"fmt"
+	"invade/treeseq"
+	"sort"
+	"strings"
+)
+
+/*
+Write the genealogy of a replicate as tskit text tables (nodes, edges, sites, mutations);
+one file per table, named <prefix>.rep<replicate>.<table>.txt (gzip compressed if the prefix ends with '.gz'); load with tskit.load_text().
+Times are in generations before the last generation; the first nodes (sampleCount) are the samples
+*/
+func WriteTreeSequence(prefix string, replicate int64, generation int64, sampleCount int64) {
+	t := treeseq.GetTables()
+	if t == nil {
+		return
+	}
+	suffix := ".txt"
+	if strings.HasSuffix(prefix, ".gz") {
+		prefix = strings.TrimSuffix(prefix, ".gz")
+		suffix = ".txt.gz"
+	}
+	filebase := fmt.Sprintf("%s.rep%d", prefix, replicate)
+
+	nodes := CreateOutput(filebase + ".nodes" + suffix)
+	defer nodes.Close()
+	nodes.WriteString("id\tis_sample\ttime\n")
+	for i, n := range t.Nodes {
+		issample := 0
+		if int64(i) < sampleCount {
+			issample = 1
+		}
+		nodes.WriteString(fmt.Sprintf("%d\t%d\t%d\n", i, issample, generation-n.Birth))
+	}
+
+	edges := CreateOutput(filebase + ".edges" + suffix)
+	defer edges.Close()
+	edges.WriteString("left\tright\tparent\tchild\n")
+	for _, e := range t.Edges {
+		edges.WriteString(fmt.Sprintf("%d\t%d\t%d\t%d\n", e.Left, e.Right, e.Parent, e.Child))
+	}
+
+	// each insertion site is a site with the ancestral state 0 (no TE) and the derived state 1 (TE)
+	siteids := make(map[int64]int)
+	positions := make([]int64, 0)
+	for _, m := range t.Mutations {
+		if _, ok := siteids[m.Site]; !ok {
+			siteids[m.Site] = 0
+			positions = append(positions, m.Site)
+		}
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i] < positions[j] })
+	sites := CreateOutput(filebase + ".sites" + suffix)
+	defer sites.Close()
+	sites.WriteString("position\tancestral_state\n")
+	for i, pos := range positions {
+		siteids[pos] = i
+		sites.WriteString(fmt.Sprintf("%d\t0\n", pos))
+	}
+
+	mutations := CreateOutput(filebase + ".mutations" + suffix)
+	defer mutations.Close()
+	mutations.WriteString("site\tnode\tderived_state\n")
+	for _, m := range t.Mutations {
+		mutations.WriteString(fmt.Sprintf("%d\t%d\t1\n", siteids[m.Site], m.Node))
+	}
+}
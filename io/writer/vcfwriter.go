@@ -0,0 +1,117 @@
+package writer
+
+import (
+	"bytes"
+	"fmt"
+	"invade/env"
+	"invade/fly"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+var vcfprefix string
+var vcfsuffix string
+var vcfsamplesize int64
+var vcfrng *rand.Rand // separate random number generator; sampling the flies does not change the simulation
+
+/*
+Setup the VCF output; one VCF file is written for each recorded generation and replicate (<prefix>.rep<replicate>.gen<generation>.vcf);
+gzip compressed if the file ends with '.gz'.
+The samplesize is the number of flies in the VCF; 0 = all flies; the flies are sampled with a random number generator seeded with seed
+*/
+func SetupVCFWriter(file string, samplesize int64, seed int64) {
+	if samplesize < 0 {
+		panic("invalid sample size for the VCF; must be larger or equal to 0")
+	}
+	vcfsuffix = ".vcf"
+	if strings.HasSuffix(file, ".gz") {
+		file = strings.TrimSuffix(file, ".gz")
+		vcfsuffix = ".vcf.gz"
+	}
+	vcfprefix = strings.TrimSuffix(file, ".vcf")
+	vcfsamplesize = samplesize
+	vcfrng = rand.New(rand.NewSource(seed))
+}
+
+/*
+Write the genotypes of the flies as VCF; each insertion site is a record, each fly a sample with phased genotypes (Hap1|Hap2).
+The flies are a random sample without replacement (see SetupVCFWriter), in the order of the population
+*/
+func WriteVCFEntry(p *fly.Population, replicate int64, generation int64) {
+	flies := sampleVCFFlies(p.Flies)
+	file := CreateOutput(fmt.Sprintf("%s.rep%d.gen%d%s", vcfprefix, replicate, generation, vcfsuffix))
+	defer file.Close()
+
+	file.WriteString("##fileformat=VCFv4.2\n")
+	file.WriteString("##source=invade\n")
+	names := env.GetChromosomeNames()
+	for i, size := range env.GetChromosomeSizes() {
+		file.WriteString(fmt.Sprintf("##contig=<ID=%s,length=%d>\n", names[i], size))
+	}
+	file.WriteString("##ALT=<ID=INS:ME,Description=\"Insertion of a transposable element\">\n")
+	file.WriteString("##INFO=<ID=CAT,Number=1,Type=String,Description=\"Category of the insertion; clu=piRNA cluster, ref=reference region, par=paramutable, tri=trigger, noe=none\">\n")
+	file.WriteString("##INFO=<ID=AF,Number=A,Type=Float,Description=\"Frequency of the insertion in the population\">\n")
+	file.WriteString("##FORMAT=<ID=GT,Number=1,Type=String,Description=\"Genotype\">\n")
+	header := new(bytes.Buffer)
+	header.WriteString("#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT")
+	for _, f := range flies {
+		header.WriteString(fmt.Sprintf("\tfly%d", f.FlyNumber))
+	}
+	file.WriteString(header.String() + "\n")
+
+	// all insertion sites of the sampled flies
+	popfreq := p.GetMHPPopulationFrequency()
+	sitemap := make(map[int64]bool)
+	for _, f := range flies {
+		for _, s := range f.GetInsertionSites() {
+			sitemap[s] = true
+		}
+	}
+	sites := make([]int64, 0, len(sitemap))
+	for s := range sitemap {
+		sites = append(sites, s)
+	}
+	sort.Slice(sites, func(i, j int) bool { return sites[i] < sites[j] })
+
+	// the haplotypes are sorted, hence a running index per haplotype suffices
+	idx := make([][2]int, len(flies))
+	for _, site := range sites {
+		chrm, chrpos := env.TranslateCoordinates(site)
+		buf := new(bytes.Buffer)
+		buf.WriteString(fmt.Sprintf("%s\t%d\t.\tN\t<INS:ME>\t.\tPASS\tCAT=%s;AF=%f\tGT", env.GetChromosomeName(chrm), chrpos, env.ScoreInsertion(site), popfreq[site]))
+		for i, f := range flies {
+			buf.WriteString(fmt.Sprintf("\t%d|%d", genotypeAt(f.Hap1, &idx[i][0], site), genotypeAt(f.Hap2, &idx[i][1], site)))
+		}
+		file.WriteString(buf.String() + "\n")
+	}
+}
+
+/*
+Random sample of flies without replacement; all flies if the sample size is 0 or not smaller than the population
+*/
+func sampleVCFFlies(flies []fly.Fly) []fly.Fly {
+	if vcfsamplesize == 0 || vcfsamplesize >= int64(len(flies)) {
+		return flies
+	}
+	indices := vcfrng.Perm(len(flies))[:vcfsamplesize]
+	sort.Ints(indices)
+	toret := make([]fly.Fly, len(indices))
+	for i, k := range indices {
+		toret[i] = flies[k]
+	}
+	return toret
+}
+
+/*
+Genotype of a sorted haplotype at the site (1 = insertion); advances the index of the haplotype
+*/
+func genotypeAt(hap []int64, index *int, site int64) int {
+	for *index < len(hap) && hap[*index] < site {
+		*index++
+	}
+	if *index < len(hap) && hap[*index] == site {
+		return 1
+	}
+	return 0
+}
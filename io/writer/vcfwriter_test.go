@@ -0,0 +1,106 @@
+package writer
+
+import (
+	"invade/env"
+	"invade/fly"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testhelper_readrecords(test *testing.T, file string) ([]string, []string) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		test.Fatalf("Missing VCF %s; %v", file, err)
+	}
+	var header []string
+	records := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		if strings.HasPrefix(line, "#CHROM") {
+			header = strings.Split(line, "\t")
+		} else if !strings.HasPrefix(line, "##") {
+			records = append(records, line)
+		}
+	}
+	return header, records
+}
+
+func TestWriteVCFEntry(test *testing.T) {
+	// a cluster of size 10 at the start of the first chromosome
+	env.SetupEnvironment([]int64{100, 100}, []int64{10, 0}, []int64{0, 0}, []bool{false}, []bool{false}, []float64{0, 0}, 0.1, 1000.0)
+	pop := fly.InitializePopulation([]fly.Fly{
+		{FlyNumber: 1, Hap1: []int64{5, 50}, Hap2: []int64{50}, FlyStat: &fly.FlyStatistic{}},
+		{FlyNumber: 2, Hap1: []int64{}, Hap2: []int64{150}, FlyStat: &fly.FlyStatistic{}},
+	})
+	var tests = []struct {
+		samplesize int64
+		wantheader string
+		want       []string
+	}{
+		{samplesize: 0, wantheader: "fly1,fly2", want: []string{
+			"1\t6\t.\tN\t<INS:ME>\t.\tPASS\tCAT=clu;AF=0.250000\tGT\t1|0\t0|0",
+			"1\t51\t.\tN\t<INS:ME>\t.\tPASS\tCAT=noe;AF=0.500000\tGT\t1|1\t0|0",
+			"2\t51\t.\tN\t<INS:ME>\t.\tPASS\tCAT=noe;AF=0.250000\tGT\t0|0\t0|1",
+		}},
+		{samplesize: 2, wantheader: "fly1,fly2", want: []string{
+			"1\t6\t.\tN\t<INS:ME>\t.\tPASS\tCAT=clu;AF=0.250000\tGT\t1|0\t0|0",
+			"1\t51\t.\tN\t<INS:ME>\t.\tPASS\tCAT=noe;AF=0.500000\tGT\t1|1\t0|0",
+			"2\t51\t.\tN\t<INS:ME>\t.\tPASS\tCAT=noe;AF=0.250000\tGT\t0|0\t0|1",
+		}},
+	}
+	for _, t := range tests {
+		prefix := filepath.Join(test.TempDir(), "invade.vcf")
+		SetupVCFWriter(prefix, t.samplesize, 1)
+		WriteVCFEntry(pop, 1, 10)
+		header, records := testhelper_readrecords(test, strings.TrimSuffix(prefix, ".vcf")+".rep1.gen10.vcf")
+		if len(header) < 9 || strings.Join(header[9:], ",") != t.wantheader {
+			test.Errorf("Incorrect samples of the VCF; got %v, want %s", header, t.wantheader)
+		}
+		if strings.Join(records, "\n") != strings.Join(t.want, "\n") {
+			test.Errorf("Incorrect records of the VCF; got\n%s\nwant\n%s", strings.Join(records, "\n"), strings.Join(t.want, "\n"))
+		}
+	}
+}
+
+func TestVCFSample(test *testing.T) {
+	env.SetupEnvironment([]int64{100, 100}, []int64{10, 0}, []int64{0, 0}, []bool{false}, []bool{false}, []float64{0, 0}, 0.1, 1000.0)
+	flies := []fly.Fly{}
+	for i := int64(1); i <= 10; i++ {
+		flies = append(flies, fly.Fly{FlyNumber: i, Hap1: []int64{i}, Hap2: []int64{}, FlyStat: &fly.FlyStatistic{}})
+	}
+	pop := fly.InitializePopulation(flies)
+
+	// the sample is drawn from a separate random number generator; the random numbers of the simulation are not affected
+	rand.Seed(7)
+	want := rand.New(rand.NewSource(7)).Int63()
+	prefix := filepath.Join(test.TempDir(), "invade.vcf")
+	SetupVCFWriter(prefix, 3, 1)
+	WriteVCFEntry(pop, 1, 0)
+	if got := rand.Int63(); got != want {
+		test.Errorf("Sampling the flies of the VCF must not draw from the random numbers of the simulation")
+	}
+
+	header, records := testhelper_readrecords(test, strings.TrimSuffix(prefix, ".vcf")+".rep1.gen0.vcf")
+	samples := header[9:]
+	if len(samples) != 3 || len(records) != 3 {
+		test.Fatalf("Incorrect sample of the VCF; got %v and %d records", samples, len(records))
+	}
+	// each sampled fly carries a single heterozygous insertion (Hap1), which is solely present in the sampled fly
+	for i, r := range records {
+		fields := strings.Split(r, "\t")
+		for k, gt := range fields[9:] {
+			want := "0|0"
+			if k == i {
+				want = "1|0"
+			}
+			if gt != want {
+				test.Errorf("Incorrect genotype of %s in record %s; got %s, want %s", samples[k], r, gt, want)
+			}
+		}
+	}
+	if samples[0] == "fly1" && samples[1] == "fly2" && samples[2] == "fly3" {
+		test.Errorf("The VCF should contain a random sample of the flies; got the first flies %v", samples)
+	}
+}
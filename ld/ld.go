@@ -0,0 +1,246 @@
+/*
+Linkage disequilibrium (D, D', r2) among TE insertion sites; haplotypes are indexed as bitsets
+*/
+package ld
+
+import (
+	"invade/env"
+	"invade/fly"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+/*
+Linkage disequilibrium between two insertion sites
+*/
+type PairLD struct {
+	Site1    int64
+	Site2    int64
+	Distance int64
+	D        float64
+	DPrime   float64 // D normalized by its maximum given the allele frequencies; between -1 and 1
+	R2       float64
+}
+
+/*
+A bin of the LD decay curve, i.e. the averages of the pairs of sites with a distance in [Start, End);
+the last bin is closed, [Start, End], and ends at the maximum distance, thus it includes the pairs at exactly the maximum distance
+*/
+type DecayBin struct {
+	Pair      string // categories of the two sites, e.g. 'clu-noe'; 'all' for all pairs
+	Start     int64
+	End       int64
+	Pairs     int64
+	D         float64
+	AbsDPrime float64
+	R2        float64
+}
+
+type Settings struct {
+	minFreq     float64 // minimum frequency of the minor allele (insertion or empty site)
+	maxDistance int64   // maximum distance between two sites of a pair
+	binSize     int64   // size of the distance bins of the LD decay
+}
+
+var settings = Settings{minFreq: 0.05, maxDistance: 100000, binSize: 10000}
+
+func SetupLD(minFreq float64, maxDistance int64, binSize int64) {
+	if minFreq <= 0.0 || minFreq > 0.5 {
+		panic("invalid minimum frequency for LD; must be larger than 0.0 and smaller or equal to 0.5")
+	}
+	if maxDistance < 1 {
+		panic("invalid maximum distance for LD; must be larger than 0")
+	}
+	if binSize < 1 {
+		panic("invalid bin size for LD; must be larger than 0")
+	}
+	settings = Settings{minFreq: minFreq, maxDistance: maxDistance, binSize: binSize}
+}
+
+/*
+Index of the haplotypes; for each site (sorted by position) a bitset of the haplotypes carrying the insertion
+*/
+type haplotypeIndex struct {
+	sites    []int64
+	bitsets  [][]uint64
+	freqs    []float64
+	hapcount int
+}
+
+/*
+Index all sites with a minor allele frequency of at least minFreq
+*/
+func newHaplotypeIndex(haps [][]int64, minFreq float64) *haplotypeIndex {
+	words := (len(haps) + 63) / 64
+	bitmap := make(map[int64][]uint64)
+	for h, hap := range haps {
+		for _, s := range hap {
+			b, ok := bitmap[s]
+			if !ok {
+				b = make([]uint64, words)
+				bitmap[s] = b
+			}
+			b[h/64] |= 1 << uint(h%64)
+		}
+	}
+	hi := haplotypeIndex{hapcount: len(haps)}
+	for s, b := range bitmap {
+		freq := float64(popcount(b)) / float64(len(haps))
+		if freq < minFreq || freq > 1.0-minFreq {
+			continue
+		}
+		hi.sites = append(hi.sites, s)
+	}
+	sort.Slice(hi.sites, func(i, j int) bool { return hi.sites[i] < hi.sites[j] })
+	for _, s := range hi.sites {
+		hi.bitsets = append(hi.bitsets, bitmap[s])
+		hi.freqs = append(hi.freqs, float64(popcount(bitmap[s]))/float64(len(haps)))
+	}
+	return &hi
+}
+
+func popcount(b []uint64) int {
+	c := 0
+	for _, w := range b {
+		c += bits.OnesCount64(w)
+	}
+	return c
+}
+
+/*
+LD between the sites i and j of the index
+*/
+func (hi *haplotypeIndex) getPairLD(i int, j int) PairLD {
+	x11 := 0
+	bi, bj := hi.bitsets[i], hi.bitsets[j]
+	for w := range bi {
+		x11 += bits.OnesCount64(bi[w] & bj[w])
+	}
+	return computeLD(hi.sites[i], hi.sites[j], float64(x11)/float64(hi.hapcount), hi.freqs[i], hi.freqs[j])
+}
+
+/*
+Compute D, D' and r2 from the frequency of haplotypes carrying both insertions (x11) and the frequencies of the insertions (p1, p2)
+*/
+func computeLD(site1 int64, site2 int64, x11 float64, p1 float64, p2 float64) PairLD {
+	//https://en.wikipedia.org/wiki/Linkage_disequilibrium
+	d := x11 - p1*p2
+	var dmax float64
+	if d >= 0 {
+		dmax = math.Min(p1*(1.0-p2), (1.0-p1)*p2)
+	} else {
+		dmax = math.Min(p1*p2, (1.0-p1)*(1.0-p2))
+	}
+	dprime := 0.0
+	if dmax > 0.0 {
+		dprime = d / dmax
+	}
+	r2 := 0.0
+	if denom := p1 * (1.0 - p1) * p2 * (1.0 - p2); denom > 0.0 {
+		r2 = d * d / denom
+	}
+	return PairLD{Site1: site1, Site2: site2, Distance: site2 - site1, D: d, DPrime: dprime, R2: r2}
+}
+
+/*
+Get the LD of all pairs of sites on the same chromosome within the maximum distance;
+only sites with a minor allele frequency of at least the minimum frequency are considered
+*/
+func GetPairs(p *fly.Population) []PairLD {
+	hi := newHaplotypeIndex(p.GetHaplotypes(), settings.minFreq)
+	chrms := make([]int64, len(hi.sites))
+	for i, s := range hi.sites {
+		chrms[i], _ = env.TranslateCoordinates(s)
+	}
+	toret := make([]PairLD, 0)
+	for i := range hi.sites {
+		for j := i + 1; j < len(hi.sites) && hi.sites[j]-hi.sites[i] <= settings.maxDistance; j++ {
+			if chrms[i] != chrms[j] {
+				break
+			}
+			toret = append(toret, hi.getPairLD(i, j))
+		}
+	}
+	return toret
+}
+
+/*
+Get the LD decay curve, i.e. the average LD of the pairs binned by distance; for all pairs and for each combination of insertion categories (e.g. cluster and non-cluster insertions)
+*/
+func GetDecay(p *fly.Population) []DecayBin {
+	bins := make(map[string]map[int64]*DecayBin)
+	add := func(pair string, pl PairLD) {
+		if _, ok := bins[pair]; !ok {
+			bins[pair] = make(map[int64]*DecayBin)
+		}
+		b, start, end := getBin(pl.Distance)
+		db, ok := bins[pair][b]
+		if !ok {
+			db = &DecayBin{Pair: pair, Start: start, End: end}
+			bins[pair][b] = db
+		}
+		db.Pairs++
+		db.D += pl.D
+		db.AbsDPrime += math.Abs(pl.DPrime)
+		db.R2 += pl.R2
+	}
+	for _, pl := range GetPairs(p) {
+		add("all", pl)
+		add(getPairCategory(pl.Site1, pl.Site2), pl)
+	}
+
+	pairs := make([]string, 0, len(bins))
+	for pair := range bins {
+		if pair != "all" {
+			pairs = append(pairs, pair)
+		}
+	}
+	sort.Strings(pairs)
+	if len(bins) > 0 {
+		pairs = append([]string{"all"}, pairs...)
+	}
+	toret := make([]DecayBin, 0)
+	for _, pair := range pairs {
+		keys := make([]int64, 0, len(bins[pair]))
+		for b := range bins[pair] {
+			keys = append(keys, b)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		for _, b := range keys {
+			db := *bins[pair][b]
+			n := float64(db.Pairs)
+			db.D /= n
+			db.AbsDPrime /= n
+			db.R2 /= n
+			toret = append(toret, db)
+		}
+	}
+	return toret
+}
+
+/*
+The distance bin of a pair of sites (index, start and end); the last bin ends at the maximum distance and includes it
+*/
+func getBin(distance int64) (int64, int64, int64) {
+	b := distance / settings.binSize
+	if last := (settings.maxDistance - 1) / settings.binSize; b > last {
+		b = last
+	}
+	end := (b + 1) * settings.binSize
+	if end > settings.maxDistance {
+		end = settings.maxDistance
+	}
+	return b, b * settings.binSize, end
+}
+
+/*
+The categories of two insertion sites, sorted; e.g. 'clu-noe'
+*/
+func getPairCategory(site1 int64, site2 int64) string {
+	c1, c2 := env.ScoreInsertion(site1), env.ScoreInsertion(site2)
+	if c2 < c1 {
+		c1, c2 = c2, c1
+	}
+	return c1 + "-" + c2
+}
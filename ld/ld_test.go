@@ -0,0 +1,100 @@
+package ld
+
+import (
+	"invade/env"
+	"invade/fly"
+	"math"
+	"testing"
+)
+
+func TestComputeLD(test *testing.T) {
+	var tests = []struct {
+		x11    float64
+		p1     float64
+		p2     float64
+		d      float64
+		dprime float64
+		r2     float64
+	}{
+		{x11: 0.5, p1: 0.5, p2: 0.5, d: 0.25, dprime: 1.0, r2: 1.0},      // complete LD
+		{x11: 0.0, p1: 0.5, p2: 0.5, d: -0.25, dprime: -1.0, r2: 1.0},    // repulsion
+		{x11: 0.25, p1: 0.5, p2: 0.5, d: 0.0, dprime: 0.0, r2: 0.0},      // linkage equilibrium
+		{x11: 0.2, p1: 0.2, p2: 0.5, d: 0.1, dprime: 1.0, r2: 0.25},      // incomplete r2 despite D'=1
+		{x11: 0.1, p1: 0.4, p2: 0.5, d: -0.1, dprime: -0.5, r2: 1.0 / 6}, // negative D
+	}
+	for _, t := range tests {
+		got := computeLD(10, 20, t.x11, t.p1, t.p2)
+		if math.Abs(got.D-t.d) > 0.00001 || math.Abs(got.DPrime-t.dprime) > 0.00001 || math.Abs(got.R2-t.r2) > 0.00001 || got.Distance != 10 {
+			test.Errorf("Incorrect LD for x11=%f p1=%f p2=%f; got %+v", t.x11, t.p1, t.p2, got)
+		}
+	}
+}
+
+func TestGetPairs(test *testing.T) {
+	env.SetupEnvironment([]int64{100, 100}, []int64{0, 0}, []int64{0, 0}, []bool{false}, []bool{false}, []float64{0, 0}, 0.1, 1000.0)
+	fly.SetupFitness(0.0, 1.0, false, false)
+	SetupLD(0.2, 50, 10)
+	defer SetupLD(0.05, 100000, 10000)
+	// 10 and 20 in complete LD; 30 is rare (below minimum frequency); 90 is too distant; 110 is on the second chromosome; 60 is fixed
+	flies := []fly.Fly{
+		*fly.NewFly([]int64{10, 20, 60, 90}, []int64{60}, fly.MALE, 0),
+		*fly.NewFly([]int64{10, 20, 60, 110}, []int64{60, 110}, fly.FEMALE, 0),
+		*fly.NewFly([]int64{30, 60}, []int64{60}, fly.MALE, 0),
+		*fly.NewFly([]int64{60}, []int64{60}, fly.FEMALE, 0),
+		*fly.NewFly([]int64{60}, []int64{60}, fly.FEMALE, 0),
+	}
+	got := GetPairs(fly.InitializePopulation(flies))
+	if len(got) != 1 {
+		test.Fatalf("Incorrect number of pairs; got %v", got)
+	}
+	if got[0].Site1 != 10 || got[0].Site2 != 20 || math.Abs(got[0].R2-1.0) > 0.00001 {
+		test.Errorf("Incorrect pair; got %+v", got[0])
+	}
+	decay := GetDecay(fly.InitializePopulation(flies))
+	if len(decay) != 2 || decay[0].Pair != "all" || decay[1].Pair != "noe-noe" || decay[0].Start != 10 || decay[0].End != 20 || decay[0].Pairs != 1 {
+		test.Errorf("Incorrect LD decay; got %+v", decay)
+	}
+}
+
+func TestGetBin(test *testing.T) {
+	defer SetupLD(0.05, 100000, 10000)
+	var tests = []struct {
+		maxDistance int64
+		binSize     int64
+		distance    int64
+		start       int64
+		end         int64
+	}{
+		{maxDistance: 20, binSize: 10, distance: 0, start: 0, end: 10},
+		{maxDistance: 20, binSize: 10, distance: 9, start: 0, end: 10},
+		{maxDistance: 20, binSize: 10, distance: 10, start: 10, end: 20},
+		{maxDistance: 20, binSize: 10, distance: 20, start: 10, end: 20}, // exactly the maximum distance; last bin is closed
+		{maxDistance: 25, binSize: 10, distance: 20, start: 20, end: 25}, // the last bin is shortened to the maximum distance
+		{maxDistance: 25, binSize: 10, distance: 25, start: 20, end: 25},
+		{maxDistance: 5, binSize: 10, distance: 5, start: 0, end: 5},
+	}
+	for _, t := range tests {
+		SetupLD(0.05, t.maxDistance, t.binSize)
+		_, start, end := getBin(t.distance)
+		if start != t.start || end != t.end {
+			test.Errorf("Incorrect bin of distance %d (max %d, bin size %d); got [%d, %d] wanted [%d, %d]", t.distance, t.maxDistance, t.binSize, start, end, t.start, t.end)
+		}
+	}
+}
+
+func TestGetDecayMaxDistance(test *testing.T) {
+	env.SetupEnvironment([]int64{100, 100}, []int64{0, 0}, []int64{0, 0}, []bool{false}, []bool{false}, []float64{0, 0}, 0.1, 1000.0)
+	fly.SetupFitness(0.0, 1.0, false, false)
+	SetupLD(0.2, 20, 10)
+	defer SetupLD(0.05, 100000, 10000)
+	// pairs at distance 10 (10-20, 20-30) and exactly at the maximum distance 20 (10-30); all in the last bin [10, 20]
+	flies := []fly.Fly{
+		*fly.NewFly([]int64{10, 20, 30}, []int64{}, fly.MALE, 0),
+		*fly.NewFly([]int64{10, 20, 30}, []int64{}, fly.FEMALE, 0),
+		*fly.NewFly([]int64{}, []int64{}, fly.MALE, 0),
+	}
+	decay := GetDecay(fly.InitializePopulation(flies))
+	if len(decay) != 2 || decay[0].Pair != "all" || decay[0].Start != 10 || decay[0].End != 20 || decay[0].Pairs != 3 {
+		test.Errorf("Incorrect LD decay; the pair at the maximum distance must be in the last bin; got %+v", decay)
+	}
+}
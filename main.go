@@ -2,13 +2,23 @@ package main
 
 import (
 	"fmt"
+	"invade/abc"
+	"invade/analytics"
 	"invade/env"
 	"invade/fly"
 	"invade/io/cmdparser"
+	"invade/io/writer"
+	"invade/ld"
 	"invade/outman"
+	"invade/poolseq"
 	"invade/sim"
+	"invade/sweep"
+	"invade/treeseq"
 	"invade/util"
 	"io/ioutil"
+	"os"
+	"os/signal"
+	"syscall"
 	//_ "net/http/pprof"
 )
 
@@ -28,30 +38,83 @@ func main_profile() {
 }
 */
 
-func main() {
+// VERSION NUMBER
+const version = "0.2.3"
 
-	// VERSION NUMBER
-	version := "0.2.3"
+func main() {
+	command, args := cmdparser.SplitSubcommand(os.Args[1:])
+	if err := run(command, args); err != nil {
+		// e.g. missing or invalid parameters (the usage is already printed) or an unknown subcommand
+		fmt.Fprintf(os.Stderr, "invade %s: %v\n", command, err)
+		os.Exit(2)
+	}
+	// the outputs are closed when the subcommand returns; e.g. a full disk or a closed pipe
+	if err := writer.GetOutputError(); err != nil {
+		fmt.Fprintf(os.Stderr, "invade %s: %v\n", command, err)
+		os.Exit(1)
+	}
+	if sim.IsInterrupted() {
+		os.Exit(130)
+	}
+}
 
-	clp := cmdparser.ParseCommandLine()
-	if clp.Silent {
-		util.InvadeLogger.SetOutput(ioutil.Discard)
+/*
+Run a subcommand; an error for an unknown subcommand or missing or invalid parameters
+*/
+func run(command string, args []string) error {
+	switch command {
+	case cmdparser.SUBSIMULATE:
+		return simulate(args)
+	case cmdparser.SUBVALIDATE:
+		return validate(args)
+	case cmdparser.SUBSUMMARIZE:
+		return summarize(args)
+	case cmdparser.SUBCONVERT:
+		return convert(args)
+	case cmdparser.SUBSWEEP:
+		return sweep.Run(args)
+	case cmdparser.SUBABC:
+		return abc.Run(args)
+	case cmdparser.SUBHELP:
+		return help(args)
 	}
+	cmdparser.PrintUsage()
+	return cmdparser.CheckSubcommand(command)
+}
 
-	util.InvadeLogger.Println(fmt.Sprintf("Welcome to InvadeGo %s", version))
-	// Get command line arguments
+/*
+The help of a subcommand, i.e. 'invade help simulate' is 'invade simulate --help'
+*/
+func help(args []string) error {
+	if len(args) == 0 || args[0] == cmdparser.SUBHELP {
+		cmdparser.PrintUsage()
+		return nil
+	}
+	if err := cmdparser.CheckSubcommand(args[0]); err != nil {
+		cmdparser.PrintUsage()
+		return err
+	}
+	return run(args[0], []string{"--help"})
+}
 
+/*
+Setup the environment and the model of the simulations; returns the seed of the random number generator
+*/
+func setupModel(clp *cmdparser.CommandLineParameters) int64 {
 	usedseed := util.SetSeed(clp.Seed) // set seed of random number generator
 	// TODO other basis stuff, threads
 
 	// Genome
 	util.InvadeLogger.Printf("parsing genome definition %s", clp.Genome)
-	genome := cmdparser.ParseRegions(clp.Genome)
+	genome, chrnames := cmdparser.ParseGenome(clp.Genome)
 	if genome == nil {
 		panic("Could not obtain valid genome definition")
 	} else {
 		util.InvadeLogger.Printf("parsed genome definition, will use: %v", genome)
 	}
+	if chrnames != nil {
+		util.InvadeLogger.Printf("parsed chromosome names, will use: %v", chrnames)
+	}
 
 	// Cluster
 	util.InvadeLogger.Printf("parsing cluster definition %s", clp.Cluster)
@@ -99,18 +162,145 @@ func main() {
 
 	util.InvadeLogger.Printf("Setting up environment; genome, piRNA cluster, reference regions, trigger sites, paramutable sites and the recombination rate")
 	env.SetupEnvironment(genome, cluster, refregion, trigger, paramutable, recrate, clp.MinFitness, float64(clp.MaxInsertions))
+	if chrnames != nil {
+		env.SetupChromosomeNames(chrnames)
+	}
 	util.InvadeLogger.Print("Setting up jumper")
 	env.SetJumper(clp.U, clp.UC)
 	util.InvadeLogger.Print("Setting up fitness function")
 	fly.SetupFitness(clp.X, clp.T, clp.Noxcluins, clp.Multiplicative)
+	util.InvadeLogger.Print("Setting up hybrid dysgenesis")
+	fly.SetupDysgenesis(clp.DysU, clp.DysSterility)
+	util.InvadeLogger.Printf("Setting up piRNA model %s", clp.PirnaModel)
+	fly.SetupPirnaModel(clp.PirnaModel)
+	util.InvadeLogger.Print("Setting up paramutation")
+	fly.SetupParamutation(clp.PTrigger, clp.PParaEstablish, clp.PParaMaintain, clp.PParaLoss)
+	util.InvadeLogger.Print("Setting up piRNA levels")
+	fly.SetupPirnaLevels(clp.PirnaQuant, clp.PirnaDeposition, clp.PirnaProduction, clp.PirnaSaturation)
+	fly.SetupZygoticSilencing(clp.ZygoticEfficacy)
+	return usedseed
+}
+
+func simulate(args []string) error {
+	clp, err := cmdparser.ParseCommandLine(cmdparser.SUBSIMULATE, args)
+	if err != nil {
+		return err
+	}
+	if clp.Silent {
+		util.InvadeLogger.SetOutput(ioutil.Discard)
+	}
+
+	util.InvadeLogger.Println(fmt.Sprintf("Welcome to InvadeGo %s", version))
+	usedseed := setupModel(clp)
+	if clp.Explain {
+		explain(clp)
+		return nil
+	}
+	util.InvadeLogger.Print("Setting up genealogy recording")
+	treeseq.SetupRecorder(clp.FileTS != "", clp.TSSimplify)
+	// the pool-seq sampling and the sample of the VCF draw from separate random number generators with seeds derived from the seed of the simulation;
+	// their random numbers are independent of the simulation and of each other
+	util.InvadeLogger.Print("Setting up pool-seq sampling")
+	poolseq.SetupSequencer(clp.PoolFlies, clp.PoolCoverage, clp.PoolDispersion, util.DeriveSeed(usedseed, 1))
+	util.InvadeLogger.Print("Setting up LD")
+	ld.SetupLD(clp.LDMinFreq, clp.LDMaxDistance, clp.LDBinSize)
 	util.InvadeLogger.Print("Setting up output manager")
-	outman.SetupOutputManager(clp.Steps, clp.ReplicateOffset, clp.FileMHP, clp.FileTally, clp.FileSFS, clp.FileDebug, clp.SampleID)
+	outman.SetupOutputManager(clp.Steps, clp.ReplicateOffset, clp.FileMHP, clp.FileTally, clp.FileSFS, clp.FileDebug, clp.FilePirna, clp.FileAge, clp.FileTS, clp.FileVCF, clp.VCFSample, util.DeriveSeed(usedseed, 2), clp.FilePoolseq, clp.FileStats, clp.FileLD, clp.FileSummary, clp.FileSumstats, clp.SampleID, clp.Format, clp.FileMain, clp.FileInfo)
+	outman.SetupAggregator(clp.FileAggregate, clp.AggregateFailed, clp.AggregateQuant, clp.Replicates, clp.Steps, clp.Generations)
+	// flush and close the output files; also after a panic or an interrupt
+	defer outman.Done()
+	// an interrupt ends the simulations after the current generation; a second interrupt terminates immediately
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupts
+		signal.Stop(interrupts)
+		util.InvadeLogger.Print("Interrupted - finishing the current generation and closing output files")
+		sim.Interrupt()
+	}()
 
 	// Simulate the thing
 	util.InvadeLogger.Print("Commencing simulations")
+	sim.SetupBasePopVCF(clp.BasePopVCF, clp.BasePopResample)
+	sim.SetupBasePopSpectrum(clp.BasePopSites, clp.BasePopSpectrum, clp.BasePopCluster)
+	sim.SetupStopCondition(clp.StopWhen, clp.StopExtra)
+	first := sim.LoadBasePopulation(clp.BasePop, clp.Popsize)
+	outman.SetupPredictions(analytics.SetupPredictions(clp.U, clp.Popsize, first), clp.AnalyticsColumns)
 	outman.WriteInfo(clp.ArgString, usedseed, version)
-	sim.SimulateInvasions(clp.BasePop, clp.Popsize, clp.Replicates, clp.Generations)
-	outman.Done() // let the output manager know the simulations are done
+	sim.SimulateInvasions(first, clp.BasePop, clp.Popsize, clp.Replicates, clp.Generations)
+	if sim.IsInterrupted() {
+		util.InvadeLogger.Print("Interrupted - the output of the completed generations is written")
+		return nil
+	}
 	util.InvadeLogger.Print("Done - thank you for using InvadeGo")
+	return nil
+}
+
+/*
+Dry run; setup the environment and the model and load the base population, without simulating
+*/
+func validate(args []string) error {
+	clp, err := cmdparser.ParseCommandLine(cmdparser.SUBVALIDATE, args)
+	if err != nil {
+		return err
+	}
+	if clp.Silent {
+		util.InvadeLogger.SetOutput(ioutil.Discard)
+	}
+	setupModel(clp)
+	sim.SetupBasePopVCF(clp.BasePopVCF, clp.BasePopResample)
+	sim.SetupBasePopSpectrum(clp.BasePopSites, clp.BasePopSpectrum, clp.BasePopCluster)
+	sim.SetupStopCondition(clp.StopWhen, clp.StopExtra)
 
+	fmt.Printf("genome\t%d bp\t%d chromosomes\n", env.GetGenomeSize(), len(env.GetChromosomeSizes()))
+	fmt.Printf("clusters\t%d bp\t%.4f of the genome\n", env.GetClusterSize(), float64(env.GetClusterSize())/float64(env.GetGenomeSize()))
+	pop := sim.LoadBasePopulation(clp.BasePop, clp.Popsize)
+	status := pop.GetStatus()
+	fmt.Printf("basepop\t%d flies\t%.2f insertions per fly\t%.2f cluster insertions per fly\tstatus %s\n", len(pop.Flies), pop.GetAverageInsertions(), pop.GetAverageClusterInsertions(), outman.GetStatusString(status))
+	if status != fly.OK {
+		return fmt.Errorf("Invalid base population; status %s", outman.GetStatusString(status))
+	}
+	fmt.Println("valid")
+	return nil
+}
+
+/*
+Print the resolved environment and the expectations for the base population (--explain)
+*/
+func explain(clp *cmdparser.CommandLineParameters) {
+	for _, line := range env.DescribeEnvironment() {
+		fmt.Println(line)
+	}
+	sim.SetupBasePopVCF(clp.BasePopVCF, clp.BasePopResample)
+	sim.SetupBasePopSpectrum(clp.BasePopSites, clp.BasePopSpectrum, clp.BasePopCluster)
+	pop := sim.LoadBasePopulation(clp.BasePop, clp.Popsize)
+	avtes := pop.GetAverageInsertions()
+	fmt.Printf("# base population: %d flies, %.4f insertions per fly (diploid)\n", len(pop.Flies), avtes)
+	fmt.Printf("# expected novel insertions per gamete: %.6f without piRNAs (u=%g), %.6f with piRNAs (uc=%g)\n",
+		env.GetExpectedNewInsertions(avtes, 0.0), clp.U, env.GetExpectedNewInsertions(avtes, 1.0), clp.UC)
+	for _, line := range analytics.SetupPredictions(clp.U, clp.Popsize, pop).GetDescription() {
+		fmt.Println("# " + line)
+	}
+}
+
+func summarize(args []string) error {
+	sp, err := cmdparser.ParseSummarizeCommandLine(args)
+	if err != nil {
+		return err
+	}
+	tables := []*cmdparser.MainTable{}
+	for _, file := range sp.Files {
+		tables = append(tables, cmdparser.ParseMainTable(file))
+	}
+	outman.SummarizeMainTables(tables, sp.Out, sp.Failed, sp.Quantiles, sp.Steps, sp.Gen)
+	return nil
+}
+
+func convert(args []string) error {
+	cp, err := cmdparser.ParseConvertCommandLine(args)
+	if err != nil {
+		return err
+	}
+	outman.ConvertMainTable(cmdparser.ParseMainTable(cp.In), cp.Format, cp.Out)
+	return nil
 }
@@ -0,0 +1,197 @@
+package outman
+
+import (
+	"fmt"
+	"invade/fly"
+	"invade/io/writer"
+	"invade/util"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	AGGREGATEEXCLUDE string = "exclude" // replicates which ended early (failed or stopped) do not contribute to the later generations
+	AGGREGATECARRY   string = "carry"   // replicates which ended early contribute their last values to the later generations
+)
+
+/*
+Aggregation of the numeric columns of the main table across replicates;
+for each recorded generation the mean, the standard deviation and the quantiles.
+The replicates are simulated one after another, thus a generation is complete once the last replicate passed it:
+the values of the recorded generations are kept in memory until then; a generation is written when the last replicate records it,
+the generations not reached by the last replicate are written when it ends
+*/
+type Aggregator struct {
+	failed      string
+	quantiles   []float64
+	replicates  int64
+	steps       int64                 // the recorded generations (--steps)
+	lastgen     int64                 // the last generation of the simulations (--gen)
+	generations map[int64][][]float64 // for each generation the values of the replicates
+	carried     []carriedValues       // the last values of the replicates which ended early
+	current     carriedValues         // the last values of the current replicate
+}
+
+type carriedValues struct {
+	generation int64
+	values     []float64
+}
+
+var aggregator *Aggregator
+
+/*
+Setup the aggregation across replicates; failed is either 'exclude' or 'carry', quantiles e.g. '0.025,0.5,0.975'
+*/
+func SetupAggregator(file string, failed string, quantiles string, replicates int64, steps int64, lastgen int64) {
+	if file == "" {
+		aggregator = nil
+		return
+	}
+	aggregator = newAggregator(failed, quantiles, replicates, steps, lastgen)
+	writer.SetupAggregateWriter(file, getAggregateHeader(aggregator.quantiles))
+}
+
+func newAggregator(failed string, quantiles string, replicates int64, steps int64, lastgen int64) *Aggregator {
+	if failed != AGGREGATEEXCLUDE && failed != AGGREGATECARRY {
+		panic(fmt.Sprintf("invalid handling of failed replicates %s; must be either 'exclude' or 'carry'", failed))
+	}
+	return &Aggregator{failed: failed, quantiles: parseQuantiles(quantiles), replicates: replicates, steps: steps, lastgen: lastgen, generations: make(map[int64][][]float64)}
+}
+
+func parseQuantiles(quantiles string) []float64 {
+	qs := []float64{}
+	if strings.TrimSpace(quantiles) == "" {
+		return qs
+	}
+	for _, s := range strings.Split(quantiles, ",") {
+		q, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil || q < 0.0 || q > 1.0 {
+			panic(fmt.Sprintf("invalid quantile %s; must be between 0.0 and 1.0", s))
+		}
+		qs = append(qs, q)
+	}
+	return qs
+}
+
+func getAggregateHeader(quantiles []float64) []string {
+	header := []string{"gen", "variable", "n", "mean", "sd"}
+	for _, q := range quantiles {
+		header = append(header, fmt.Sprintf("q%g", q))
+	}
+	return header
+}
+
+/*
+Record the population of a replicate; the values of the recorded generations (--steps) are collected, the last values are kept for carrying
+*/
+func (a *Aggregator) record(p *fly.Population, replicate int64, generation int64, recorded bool) {
+	a.recordValues(GetNumericValues(p), replicate, generation, recorded)
+}
+
+func (a *Aggregator) recordValues(values []float64, replicate int64, generation int64, recorded bool) {
+	a.current = carriedValues{generation: generation, values: values}
+	if !recorded {
+		return
+	}
+	if replicate == a.replicates-1 {
+		// the last replicate; the generation is complete
+		a.writeGeneration(generation, append(a.generations[generation], values))
+		delete(a.generations, generation)
+	} else {
+		a.generations[generation] = append(a.generations[generation], values)
+	}
+}
+
+/*
+The replicate ended, either at the last generation, due to failure or due to a stop condition
+*/
+func (a *Aggregator) finishReplicate(replicate int64) {
+	last := a.current
+	if last.values != nil {
+		a.carried = append(a.carried, last)
+	}
+	a.current = carriedValues{}
+	if replicate != a.replicates-1 {
+		return
+	}
+	// the last replicate ended; write the remaining generations, i.e. which were not reached by the last replicate
+	if a.failed == AGGREGATECARRY {
+		// with carrying all recorded generations up to the last one are written, even if no replicate reached them
+		for gen := (last.generation/a.steps + 1) * a.steps; gen <= a.lastgen; gen += a.steps {
+			if _, ok := a.generations[gen]; !ok {
+				a.generations[gen] = [][]float64{}
+			}
+		}
+	}
+	remaining := []int64{}
+	for gen := range a.generations {
+		remaining = append(remaining, gen)
+	}
+	sort.Slice(remaining, func(i, j int) bool { return remaining[i] < remaining[j] })
+	for _, gen := range remaining {
+		a.writeGeneration(gen, a.generations[gen])
+		delete(a.generations, gen)
+	}
+}
+
+/*
+Write the aggregated values of a generation; with 'carry' the last values of replicates which ended before the generation are included
+*/
+func (a *Aggregator) writeGeneration(generation int64, values [][]float64) {
+	if a.failed == AGGREGATECARRY {
+		for _, c := range a.carried {
+			if c.generation < generation {
+				values = append(values, c.values)
+			}
+		}
+	}
+	for i, column := range NumericColumns {
+		colvalues := make([]float64, len(values))
+		for k, v := range values {
+			colvalues[k] = v[i]
+		}
+		writer.WriteAggregateEntry(append([]string{fmt.Sprintf("%d", generation), column}, a.aggregate(colvalues)...))
+	}
+}
+
+/*
+The number of replicates, the mean, the standard deviation and the quantiles of the values; NA if undefined
+*/
+func (a *Aggregator) aggregate(values []float64) []string {
+	n := len(values)
+	mean, sd := math.NaN(), math.NaN()
+	if n > 0 {
+		mean = 0.0
+		for _, v := range values {
+			mean += v
+		}
+		mean /= float64(n)
+	}
+	if n > 1 {
+		ss := 0.0
+		for _, v := range values {
+			ss += (v - mean) * (v - mean)
+		}
+		sd = math.Sqrt(ss / float64(n-1))
+	}
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	toret := []string{fmt.Sprintf("%d", n), formatAggregate(mean), formatAggregate(sd)}
+	for _, q := range a.quantiles {
+		if n == 0 {
+			toret = append(toret, formatAggregate(math.NaN()))
+		} else {
+			toret = append(toret, formatAggregate(util.Quantile(sorted, q)))
+		}
+	}
+	return toret
+}
+
+func formatAggregate(v float64) string {
+	if math.IsNaN(v) {
+		return "NA"
+	}
+	return fmt.Sprintf("%.4f", v)
+}
@@ -0,0 +1,63 @@
+package outman
+
+import (
+	"fmt"
+	"invade/io/cmdparser"
+	"regexp"
+	"strings"
+)
+
+var sampleColumn = regexp.MustCompile(`^sample\d+$`)
+
+/*
+Convert a main table into the given format; written to stdout if no file is provided;
+converting into the legacy format restores the '|' separator columns
+*/
+func ConvertMainTable(t *cmdparser.MainTable, format string, file string) {
+	if !isValidFormat(format) {
+		panic(fmt.Sprintf("invalid format of the main table %s", format))
+	}
+	out := openMainOutput(file)
+	defer out.Close()
+	samples := 0
+	for _, n := range t.Names {
+		if sampleColumn.MatchString(n) {
+			samples++
+		}
+	}
+	if format == FORMATLEGACY {
+		out.WriteString(getLegacyHeader(t.Names[:len(t.Names)-samples]) + "\n")
+	}
+	for i, row := range t.Rows {
+		writeRecord(out, format, toMainRecord(t.Names, row, samples), i == 0, samples)
+	}
+}
+
+/*
+A row of a main table as record; with the separators of the legacy format
+*/
+func toMainRecord(names []string, values []string, samples int) *mainRecord {
+	rec := &mainRecord{}
+	for i := range names {
+		if legacySeparators[names[i]] || i == len(names)-samples {
+			rec.separate()
+		}
+		rec.add(names[i], values[i])
+	}
+	return rec
+}
+
+/*
+The header of the legacy format; like the header written by WriteInfo, the sample IDs are a single column
+*/
+func getLegacyHeader(names []string) string {
+	fields := []string{}
+	for _, n := range names {
+		if legacySeparators[n] {
+			fields = append(fields, "|")
+		}
+		fields = append(fields, n)
+	}
+	fields = append(fields, "|", "sampleids")
+	return "# " + strings.Join(fields, "\t")
+}
@@ -0,0 +1,144 @@
+package outman
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"invade/io/writer"
+	"strconv"
+	"strings"
+)
+
+/*
+Formats of the main table;
+legacy: tab separated with '|' separator columns and a '# ' prefixed header (default);
+tsv, csv: single line header with the column names, no separator columns;
+jsonl: one JSON object per line
+*/
+const (
+	FORMATLEGACY = "legacy"
+	FORMATTSV    = "tsv"
+	FORMATCSV    = "csv"
+	FORMATJSONL  = "jsonl"
+)
+
+/*
+The columns of the main table which are preceded by a '|' separator column in the legacy format (see RecordPopulation)
+*/
+var legacySeparators = map[string]bool{"fwte": true, "phase": true, "fwcli": true, "fwpar_yespi": true, "piori": true, "fwpi_mat": true, "pred_avtes": true}
+
+/*
+A row of the main table; the names and values of the columns, in the order of the columns.
+Separators ('|') are solely used in the legacy format
+*/
+type mainRecord struct {
+	names      []string
+	values     []string
+	separators []bool
+}
+
+func (r *mainRecord) add(name string, value string) {
+	r.names = append(r.names, name)
+	r.values = append(r.values, value)
+	r.separators = append(r.separators, false)
+}
+
+func (r *mainRecord) separate() {
+	r.names = append(r.names, "|")
+	r.values = append(r.values, "|")
+	r.separators = append(r.separators, true)
+}
+
+/*
+The names and values of the columns without separators
+*/
+func (r *mainRecord) getColumns() ([]string, []string) {
+	names := make([]string, 0, len(r.names))
+	values := make([]string, 0, len(r.values))
+	for i := range r.names {
+		if !r.separators[i] {
+			names = append(names, r.names[i])
+			values = append(values, r.values[i])
+		}
+	}
+	return names, values
+}
+
+func isValidFormat(format string) bool {
+	return format == FORMATLEGACY || format == FORMATTSV || format == FORMATCSV || format == FORMATJSONL
+}
+
+/*
+Open the output of the main table; stdout if no file is provided; gzip compressed if the file ends with '.gz'
+*/
+func openMainOutput(file string) *writer.Output {
+	if file == "" {
+		return writer.StdoutOutput()
+	}
+	return writer.CreateOutput(file)
+}
+
+/*
+Write a row of the main table in the requested format; the header of the tsv and csv format is written with the first row
+*/
+func writeMainRecord(r *mainRecord) {
+	writeRecord(outman.mainout, outman.format, r, !outman.headerWritten, len(outman.sampleparsed))
+	outman.headerWritten = true
+}
+
+/*
+Write a row in the given format, with the header if requested (tsv and csv); the last 'samples' columns are the sample IDs
+*/
+func writeRecord(out *writer.Output, format string, r *mainRecord, header bool, samples int) {
+	if format == FORMATLEGACY {
+		buf := new(bytes.Buffer)
+		for _, v := range r.values {
+			buf.WriteString(v + "\t")
+		}
+		out.WriteString(buf.String() + "\n")
+		return
+	}
+	names, values := r.getColumns()
+	if format == FORMATJSONL {
+		out.WriteString(formatJSONRecord(names, values, len(names)-samples) + "\n")
+		return
+	}
+	if format == FORMATTSV {
+		if header {
+			out.WriteString(strings.Join(names, "\t") + "\n")
+		}
+		out.WriteString(strings.Join(values, "\t") + "\n")
+		return
+	}
+	// csv; e.g. quotes the frequencies of the piRNA origins, which contain commas
+	cw := csv.NewWriter(out)
+	if header {
+		cw.Write(names)
+	}
+	cw.Write(values)
+	cw.Flush()
+}
+
+/*
+Format a row as JSON object; numeric values are numbers, the sample IDs (the columns starting at sampleStart) and other values are strings
+*/
+func formatJSONRecord(names []string, values []string, sampleStart int) string {
+	buf := new(bytes.Buffer)
+	buf.WriteString("{")
+	for i := range names {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		key, _ := json.Marshal(names[i])
+		buf.Write(key)
+		buf.WriteString(":")
+		if _, err := strconv.ParseFloat(values[i], 64); err == nil && i < sampleStart {
+			buf.WriteString(values[i])
+		} else {
+			val, _ := json.Marshal(values[i])
+			buf.Write(val)
+		}
+	}
+	buf.WriteString("}")
+	return buf.String()
+}
@@ -1,6 +1,16 @@
 package outman
 
-import "testing"
+import (
+	"invade/analytics"
+	"invade/env"
+	"invade/fly"
+	"invade/io/cmdparser"
+	"invade/io/writer"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
 
 // command line, run all tests "go test ./..." yes three points
 
@@ -27,3 +37,196 @@ func TestOriginManager(test *testing.T) {
 
 	}
 }
+
+func TestMainRecord(test *testing.T) {
+	rec := new(mainRecord)
+	rec.add("rep", "1")
+	rec.add("popstat", "ok")
+	rec.separate()
+	rec.add("orifreq", "1:0.50,2:0.50")
+	rec.add("sample1", "17")
+	names, values := rec.getColumns()
+	if len(names) != 4 || names[2] != "orifreq" || values[3] != "17" {
+		test.Errorf("Incorrect columns without separators; got %v %v", names, values)
+	}
+	want := `{"rep":1,"popstat":"ok","orifreq":"1:0.50,2:0.50","sample1":"17"}`
+	if got := formatJSONRecord(names, values, 3); got != want {
+		test.Errorf("Incorrect JSON record; got %s, want %s", got, want)
+	}
+}
+
+func TestReplicateSummary(test *testing.T) {
+	env.SetupEnvironment([]int64{1000, 1000}, []int64{100, 100}, []int64{0, 0}, []bool{}, []bool{}, []float64{0, 0}, 0.1, 1000.0)
+	fly.SetupFitness(0, 0, false, false)
+	fly.SetupPirnaModel("trap")
+	newPopulation := func(haps ...[]int64) *fly.Population {
+		flies := []fly.Fly{}
+		for i, h := range haps {
+			sex := fly.MALE
+			if i%2 == 0 {
+				sex = fly.FEMALE
+			}
+			flies = append(flies, *fly.NewFly(h, []int64{}, sex, 0))
+		}
+		return fly.InitializePopulation(flies)
+	}
+	s := newReplicateSummary(1)
+	s.update(newPopulation([]int64{500}, []int64{}), 0, fly.OK)              // no cluster insertion
+	s.update(newPopulation([]int64{500, 600}, []int64{700, 800}), 1, fly.OK) // peak
+	s.update(newPopulation([]int64{5}, []int64{}), 2, fly.OK)                // cluster insertion triggers piRNAs
+	s.update(newPopulation([]int64{}, []int64{}), 3, fly.FAIL0)
+	want := []string{"1", "2", "NA", "NA", "2", "2.00", "1", "NA", "3", "fail-0", "3"}
+	got := s.getValues()
+	if len(got) != len(getSummaryHeader()) {
+		test.Errorf("Incorrect number of summary columns; got %d", len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			test.Errorf("Incorrect summary column %s; want %s, got %s", getSummaryHeader()[i], want[i], got[i])
+		}
+	}
+}
+
+func TestAggregate(test *testing.T) {
+	a := &Aggregator{quantiles: []float64{0.0, 0.5, 1.0}}
+	var tests = []struct {
+		values []float64
+		want   []string
+	}{
+		{values: []float64{}, want: []string{"0", "NA", "NA", "NA", "NA", "NA"}},
+		{values: []float64{2.0}, want: []string{"1", "2.0000", "NA", "2.0000", "2.0000", "2.0000"}},
+		{values: []float64{3.0, 1.0, 2.0}, want: []string{"3", "2.0000", "1.0000", "1.0000", "2.0000", "3.0000"}},
+	}
+	for _, t := range tests {
+		got := a.aggregate(t.values)
+		for i := range t.want {
+			if got[i] != t.want[i] {
+				test.Errorf("Incorrect aggregation of %v; want %v, got %v", t.values, t.want, got)
+				break
+			}
+		}
+	}
+}
+
+func TestAggregatorFailedReplicates(test *testing.T) {
+	var tests = []struct {
+		failed string
+		want   map[string]string // generation -> number of replicates and mean of the first column
+	}{
+		{failed: AGGREGATEEXCLUDE, want: map[string]string{"0": "2 1.5000", "10": "2 3.5000", "20": "1 6.0000"}},
+		{failed: AGGREGATECARRY, want: map[string]string{"0": "2 1.5000", "10": "2 3.5000", "20": "2 5.5000", "30": "2 6.0000"}},
+	}
+	for _, t := range tests {
+		file := filepath.Join(test.TempDir(), "aggregate.txt")
+		SetupAggregator(file, t.failed, "0.5", 2, 10, 30)
+		values := func(v float64) []float64 {
+			toret := make([]float64, len(NumericColumns))
+			toret[0] = v
+			return toret
+		}
+		// the first replicate fails at generation 15, the second at generation 25
+		aggregator.recordValues(values(1.0), 0, 0, true)
+		aggregator.recordValues(values(3.0), 0, 10, true)
+		aggregator.recordValues(values(5.0), 0, 15, false)
+		aggregator.finishReplicate(0)
+		aggregator.recordValues(values(2.0), 1, 0, true)
+		aggregator.recordValues(values(4.0), 1, 10, true)
+		aggregator.recordValues(values(6.0), 1, 20, true)
+		aggregator.recordValues(values(7.0), 1, 25, false)
+		aggregator.finishReplicate(1)
+		writer.CloseAggregateWriter()
+
+		content, _ := os.ReadFile(file)
+		got := make(map[string]string)
+		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n")[1:] {
+			tmp := strings.Split(line, "\t")
+			if tmp[1] == NumericColumns[0] {
+				got[tmp[0]] = tmp[2] + " " + tmp[3]
+			}
+		}
+		if len(got) != len(t.want) {
+			test.Errorf("Incorrect aggregated generations with %s; want %v, got %v", t.failed, t.want, got)
+		}
+		for gen, w := range t.want {
+			if got[gen] != w {
+				test.Errorf("Incorrect aggregation of generation %s with %s; want %s, got %s", gen, t.failed, w, got[gen])
+			}
+		}
+	}
+}
+
+func TestLegacyConversion(test *testing.T) {
+	names := []string{"rep", "fmale", "fwte", "piori", "orifreq", "sample1"}
+	rec := toMainRecord(names, []string{"1", "0.5", "0.2", "1", "1:1.00", "a"}, 1)
+	if got := strings.Join(rec.values, " "); got != "1 0.5 | 0.2 | 1 1:1.00 | a" {
+		test.Errorf("Incorrect separators of the legacy format; got %s", got)
+	}
+	if got := getLegacyHeader(names[:5]); got != "# rep\tfmale\t|\tfwte\t|\tpiori\torifreq\t|\tsampleids" {
+		test.Errorf("Incorrect legacy header; got %s", got)
+	}
+}
+
+func TestSummarizeMainTables(test *testing.T) {
+	table := &cmdparser.MainTable{Names: append([]string{"rep", "gen", "popstat"}, NumericColumns...)}
+	row := func(rep string, gen string, popstat string, avtes string) []string {
+		r := []string{rep, gen, popstat}
+		for _, c := range NumericColumns {
+			if c == "avtes" {
+				r = append(r, avtes)
+			} else {
+				r = append(r, "0")
+			}
+		}
+		return r
+	}
+	// the second replicate failed at generation 13, the third at generation 5; the failures are solely carried
+	table.Rows = [][]string{row("1", "0", "ok", "1"), row("1", "10", "ok", "3"), row("1", "20", "ok", "5"),
+		row("2", "0", "ok", "1"), row("2", "10", "ok", "7"), row("2", "13", "fail-0", "0"),
+		row("3", "0", "ok", "1"), row("3", "5", "fail-0", "0")}
+	for _, failed := range []string{AGGREGATEEXCLUDE, AGGREGATECARRY} {
+		file := filepath.Join(test.TempDir(), "summary.txt")
+		SummarizeMainTables([]*cmdparser.MainTable{table}, file, failed, "0.5", 0, 0)
+		content, _ := os.ReadFile(file)
+		lines := map[string]string{}
+		for _, l := range strings.Split(string(content), "\n") {
+			if f := strings.SplitN(l, "\t", 3); len(f) == 3 && f[1] == "avtes" {
+				lines[f[0]] = f[2]
+			}
+		}
+		want := map[string]string{"0": "3\t1.0000\t0.0000\t1.0000", "10": "2\t5.0000\t2.8284\t5.0000", "20": "1\t5.0000\tNA\t5.0000"}
+		if failed == AGGREGATECARRY {
+			want["10"] = "3\t3.3333\t3.5119\t3.0000"
+			want["20"] = "3\t1.6667\t2.8868\t0.0000"
+		}
+		if len(lines) != len(want) {
+			test.Errorf("Incorrect summarized generations (%s); want %v, got %v", failed, want, lines)
+		}
+		for gen, w := range want {
+			if lines[gen] != w {
+				test.Errorf("Incorrect summary (%s) of generation %s; want %s, got %s", failed, gen, w, lines[gen])
+			}
+		}
+	}
+}
+
+func TestWriteInfo(test *testing.T) {
+	predictions := &analytics.Predictions{ClusterFraction: 0.1, HaploidInsertions: 10.0, DiploidInsertions: 20.0, SilencingInsertions: 46.0, GenFirstCluster: 30.0, FixationProbability: 0.01, GenFixation: 40.0}
+	for _, format := range []string{FORMATLEGACY, FORMATTSV} {
+		dir := test.TempDir()
+		mainfile, infofile := filepath.Join(dir, "main.txt"), filepath.Join(dir, "info.txt")
+		outman = OutputManager{format: format, fileInfo: infofile, mainout: writer.CreateOutput(mainfile), predictions: predictions}
+		WriteInfo("--N 10", 5, "0.1")
+		outman.mainout.Close()
+
+		// the info file is written for all formats; solely the legacy main table has the info in the header
+		content, _ := os.ReadFile(infofile)
+		lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+		if len(lines) != 5 || lines[0] != "args: --N 10" || lines[1] != "version 0.1, seed: 5" || !strings.HasPrefix(lines[2], "analytics: trap model") {
+			test.Errorf("Incorrect info file (%s); got %v", format, lines)
+		}
+		main, _ := os.ReadFile(mainfile)
+		if got := strings.Contains(string(main), "# analytics: trap model"); got != (format == FORMATLEGACY) {
+			test.Errorf("Incorrect header of the main table (%s); got %s", format, string(main))
+		}
+	}
+}
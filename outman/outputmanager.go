@@ -3,15 +3,22 @@ package outman
 import (
 	"bytes"
 	"fmt"
+	"invade/analytics"
 	"invade/fly"
 	"invade/io/writer"
+	"invade/popgen"
+	"invade/util"
+	"math"
 	"strings"
 )
 
 var outman OutputManager
 
 func SetupOutputManager(steps int64, replicateOffset int64,
-	fileMHP string, fileTally string, fileSFS string, fileDebug string, sampleid string) {
+	fileMHP string, fileTally string, fileSFS string, fileDebug string, filePirna string, fileAge string, fileTS string, fileVCF string, vcfSample int64, vcfSeed int64, filePoolseq string, fileStats string, fileLD string, fileSummary string, fileSumstats string, sampleid string, format string, fileMain string, fileInfo string) {
+	if !isValidFormat(format) {
+		panic("unknown format of the main table: " + format)
+	}
 	sampleparsed := []string{}
 	if strings.Contains(sampleid, ",") {
 		sampleparsed = strings.Split(sampleid, ",")
@@ -25,6 +32,32 @@ func SetupOutputManager(steps int64, replicateOffset int64,
 	if fileDebug != "" {
 		writer.SetupDebugWriter(fileDebug)
 	}
+	if filePirna != "" {
+		writer.SetupPirnaWriter(filePirna)
+	}
+	if fileAge != "" {
+		writer.SetupAgeWriter(fileAge)
+	}
+	if fileVCF != "" {
+		writer.SetupVCFWriter(fileVCF, vcfSample, vcfSeed)
+	}
+	if filePoolseq != "" {
+		writer.SetupPoolseqWriter(filePoolseq)
+	}
+	if fileStats != "" {
+		writer.SetupStatsWriter(fileStats)
+	}
+	if fileLD != "" {
+		writer.SetupLDWriter(fileLD)
+	}
+	if fileSummary != "" {
+		writer.SetupSummaryWriter(fileSummary, getSummaryHeader())
+	}
+	if fileSumstats != "" {
+		writer.SetupSumstatsWriter(fileSumstats, append([]string{"rep", "gen", "popstat"}, popgen.GetSummaryStatisticNames()...))
+	}
+	// the origin of insertions is solely tracked when needed for the output
+	fly.SetupInsertionTracking(fileMHP != "" || fileAge != "")
 
 	outman = OutputManager{
 		steps:           steps,
@@ -33,8 +66,20 @@ func SetupOutputManager(steps int64, replicateOffset int64,
 		fileTally:       fileTally,
 		fileDebug:       fileDebug,
 		fileSFS:         fileSFS,
+		filePirna:       filePirna,
+		fileAge:         fileAge,
+		fileTS:          fileTS,
+		fileVCF:         fileVCF,
+		filePoolseq:     filePoolseq,
+		fileStats:       fileStats,
+		fileLD:          fileLD,
+		fileSummary:     fileSummary,
+		fileSumstats:    fileSumstats,
+		fileInfo:        fileInfo,
 		sampleid:        sampleid,
 		sampleparsed:    sampleparsed,
+		format:          format,
+		mainout:         openMainOutput(fileMain),
 	}
 
 }
@@ -46,13 +91,55 @@ type OutputManager struct {
 	fileSFS         string
 	fileTally       string
 	fileDebug       string
+	filePirna       string
+	fileAge         string
+	fileTS          string
+	fileVCF         string
+	filePoolseq     string
+	fileStats       string
+	fileLD          string
+	fileSummary     string
+	fileSumstats    string
+	fileInfo        string // arguments, seed and analytical predictions; the structured formats of the main table solely have a header line
 	sampleid        string
 	sampleparsed    []string
+	format          string         // format of the main table
+	mainout         *writer.Output // output of the main table; stdout by default
+	headerWritten   bool
+	summary         *ReplicateSummary // milestones of the current replicate
+	laststatus      fly.PopStatus     // status of the last recorded generation of the current replicate
+	predictions     *analytics.Predictions
+	referenceCols   bool // the predicted copy number and the deviation of the simulation as columns of the main table
+}
+
+/*
+Setup the analytical predictions of the trap model; written to the header of the main table and optionally as reference columns (pred_avtes, rel_avtes)
+*/
+func SetupPredictions(p *analytics.Predictions, columns bool) {
+	outman.predictions = p
+	outman.referenceCols = columns
 }
 
 func WriteInfo(userargs string, usedseed int64, version string) {
-	fmt.Println(fmt.Sprintf("# args: %s", userargs))
-	fmt.Println(fmt.Sprintf("# version %s, seed: %d", version, usedseed))
+	info := []string{fmt.Sprintf("args: %s", userargs), fmt.Sprintf("version %s, seed: %d", version, usedseed)}
+	if outman.predictions != nil {
+		for _, line := range outman.predictions.GetDescription() {
+			info = append(info, fmt.Sprintf("analytics: %s", line))
+		}
+	}
+	if outman.fileInfo != "" {
+		writer.WriteInfoFile(outman.fileInfo, info)
+	}
+	if outman.format != FORMATLEGACY {
+		// structured formats have a single line header (written with the first row); args, seed and predictions are logged
+		for _, line := range info {
+			util.InvadeLogger.Print(line)
+		}
+		return
+	}
+	for _, line := range info {
+		fmt.Fprintln(outman.mainout, "# "+line)
+	}
 	// General info about the columns
 	buf := new(bytes.Buffer)
 	buf.WriteString("# ")
@@ -82,9 +169,18 @@ func WriteInfo(userargs string, usedseed int64, version string) {
 	buf.WriteString("|\t")           // |
 	buf.WriteString("piori\t")       // number of independent origins for small RNAs; i.e. number of maternal lineages
 	buf.WriteString("orifreq\t")     // frequencies of each independent origin; minimum frequency 0.01
+	buf.WriteString("|\t")           // |
+	buf.WriteString("fwpi_mat\t")    // fraction of individuals with maternally deposited piRNAs
+	buf.WriteString("fwpi_zyg\t")    // fraction of individuals with solely zygotically produced piRNAs
+	buf.WriteString("fdys\t")        // fraction of dysgenic individuals (mother without piRNAs, father with TEs)
+	if outman.referenceCols {
+		buf.WriteString("|\t")
+		buf.WriteString("pred_avtes\t") // predicted insertions per fly at the equilibrium of the trap model
+		buf.WriteString("rel_avtes\t")  // insertions per fly relative to the prediction
+	}
 	buf.WriteString("|\t")
 	buf.WriteString("sampleids")
-	fmt.Println(buf.String())
+	fmt.Fprintln(outman.mainout, buf.String())
 
 }
 
@@ -93,20 +189,71 @@ func WriteInfo(userargs string, usedseed int64, version string) {
 func Done() {
 	writer.CloseMHPWriter()
 	writer.CloseDebugWriter()
+	writer.ClosePirnaWriter()
+	writer.CloseAgeWriter()
+	writer.ClosePoolseqWriter()
+	writer.CloseStatsWriter()
+	writer.CloseLDWriter()
+	writer.CloseSummaryWriter()
+	writer.CloseSumstatsWriter()
+	writer.CloseAggregateWriter()
+	outman.mainout.Close()
+	writer.CloseAllOutputs() // e.g. the outputs of the VCF that were not closed due to a panic
 
 }
 
+/*
+Write the genealogy of a replicate (tree sequence); the genealogy is simplified with respect to the flies of the last generation
+*/
+func RecordGenealogy(p *fly.Population, replicate int64) {
+	if outman.fileTS == "" {
+		return
+	}
+	p.SimplifyGenealogy()
+	writer.WriteTreeSequence(outman.fileTS, replicate+outman.replicateOffset, p.GetGeneration(), 2*p.Size())
+}
+
+/*
+Let the output manager know that a replicate ended (at the last generation, due to a failure or a stop condition);
+writes the summary of the replicate, i.e. the milestones of the invasion, and the summary statistics of the last generation
+*/
+func FinishReplicate(p *fly.Population, replicate int64) {
+	if outman.fileSummary != "" {
+		writer.WriteSummaryEntry(outman.summary.getValues())
+	}
+	if outman.fileSumstats != "" {
+		values := []string{fmt.Sprintf("%d", replicate+outman.replicateOffset), fmt.Sprintf("%d", p.GetGeneration()), GetStatusString(outman.laststatus)}
+		for _, s := range popgen.GetSummaryStatistics(p) {
+			values = append(values, fmt.Sprintf("%f", s))
+		}
+		writer.WriteSumstatsEntry(values)
+	}
+	if aggregator != nil {
+		aggregator.finishReplicate(replicate)
+	}
+}
+
 func RecordPopulation(p *fly.Population, replicate int64, generation int64, popstat fly.PopStatus) {
 	if generation == 0 {
 		originman = newOriginManger()
+		outman.summary = newReplicateSummary(replicate + outman.replicateOffset)
 	}
-	// Write populations if it is failure (including base population!)
+	outman.laststatus = popstat
+	if outman.fileSummary != "" {
+		outman.summary.update(p, generation, popstat)
+	}
+	// Write populations if it is failure or the last generation of a stopped simulation (including base population!)
 	// or else if the generation has the required step (modulo == 0, hence including base population)
-	if popstat == fly.FAIL0 || popstat == fly.FAILW || popstat == fly.FAILSEX || popstat == fly.FAILMAX {
+	stepped := generation%outman.steps == 0
+	if popstat == fly.FAIL0 || popstat == fly.FAILW || popstat == fly.FAILSEX || popstat == fly.FAILMAX || popstat == fly.FAILSTER || popstat == fly.STOPPED {
 		writePopulation(p, replicate, generation, popstat)
-	} else if popstat == fly.OK && generation%outman.steps == 0 {
+	} else if popstat == fly.OK && stepped {
 		writePopulation(p, replicate, generation, popstat)
 	}
+	if aggregator != nil {
+		// the values of failed populations are solely used for carrying
+		aggregator.record(p, replicate, generation, stepped && (popstat == fly.OK || popstat == fly.STOPPED))
+	}
 	// Ignore if neither an unusual status or the requested recording generation
 }
 
@@ -117,6 +264,24 @@ func writePopulation(p *fly.Population, replicate int64, generation int64, popst
 	if outman.fileDebug != "" {
 		writer.WriteDebugEntry(p, replicate+outman.replicateOffset, generation)
 	}
+	if outman.filePirna != "" {
+		writer.WritePirnaEntry(p, replicate+outman.replicateOffset, generation)
+	}
+	if outman.fileAge != "" {
+		writer.WriteAgeEntry(p, replicate+outman.replicateOffset, generation)
+	}
+	if outman.fileVCF != "" {
+		writer.WriteVCFEntry(p, replicate+outman.replicateOffset, generation)
+	}
+	if outman.filePoolseq != "" {
+		writer.WritePoolseqEntry(p, replicate+outman.replicateOffset, generation)
+	}
+	if outman.fileStats != "" {
+		writer.WriteStatsEntry(p, replicate+outman.replicateOffset, generation)
+	}
+	if outman.fileLD != "" {
+		writer.WriteLDEntry(p, replicate+outman.replicateOffset, generation)
+	}
 	if outman.fileSFS != "" {
 
 	}
@@ -126,52 +291,60 @@ func writePopulation(p *fly.Population, replicate int64, generation int64, popst
 	// INVADE
 	// #replicate	generation	| fwt	w	tes	popfreq	fixed	| fwcli	cluins	cluins_popfreq	cluins_fixed	phase	| novel	sites	clusites	tes_stdev	cluins_stdev	fw0	w_min	popsize
 
-	buf := new(bytes.Buffer)
-	buf.WriteString(fmt.Sprintf("%d\t", replicate+outman.replicateOffset))           // replicate
-	buf.WriteString(fmt.Sprintf("%d\t", generation))                                 // generation
-	buf.WriteString(fmt.Sprintf("%s\t", getStatusString(popstat)))                   // status
-	buf.WriteString(fmt.Sprintf("%.2f\t", p.GetMaleFrequency()))                     // fmales
-	buf.WriteString("|\t")                                                           // |
-	buf.WriteString(fmt.Sprintf("%.2f\t", p.GetWithTEFrequency()))                   // fwte
-	buf.WriteString(fmt.Sprintf("%.2f\t", p.GetAverageFitness()))                    // w
-	buf.WriteString(fmt.Sprintf("%.2f\t", p.GetMinimumFitness()))                    // minw
-	buf.WriteString(fmt.Sprintf("%.2f\t", p.GetAverageInsertions()))                 // avtes
-	buf.WriteString(fmt.Sprintf("%.2f\t", p.GetAveragePopulationFrequency()))        //  popfreq all
-	buf.WriteString(fmt.Sprintf("%d\t", len(p.GetFixedInsertions())))                // fixed insertions
-	buf.WriteString("|\t")                                                           // |
-	buf.WriteString(fmt.Sprintf("%s\t", getPhaseString(p.GetPhase())))               // Phase
-	buf.WriteString(fmt.Sprintf("%.2f\t", p.GetWithPirnaFrequency()))                // fw piRNAs (either cluster or para)
-	buf.WriteString("|\t")                                                           // |
-	buf.WriteString(fmt.Sprintf("%.2f\t", p.GetWithClusterInsertionFrequency()))     // fw cluster insertions
-	buf.WriteString(fmt.Sprintf("%.2f\t", p.GetAverageClusterInsertions()))          //  number of cluster insertions
-	buf.WriteString(fmt.Sprintf("%d\t", p.GetFixedClusterInsertionCount()))          // get fixed cluster insertions
-	buf.WriteString("|\t")                                                           // |
-	buf.WriteString(fmt.Sprintf("%.2f\t", p.GetWithParamutationYesPirnaFrequency())) // fw insertion into paramutable locus and piRNAs
-	buf.WriteString(fmt.Sprintf("%.2f\t", p.GetWithParamutationNoPirnaFrequency()))  // fw insertion into paramutable locus but NO piRNAs
-	buf.WriteString(fmt.Sprintf("%.2f\t", p.GetAverageParaInsertions()))             //  number of paramutable insertions
-	buf.WriteString(fmt.Sprintf("%d\t", p.GetFixedParaInsertionCount()))             // get fixed paramutable loci
-	buf.WriteString("|\t")
-	buf.WriteString(fmt.Sprintf("%d\t", p.GetPirnaOriginCount()))
-	buf.WriteString(formatOriginFreq(p.GetPirnaOriginFrequencies(), 0.01))
-	buf.WriteString("\t")
-
+	rec := new(mainRecord)
+	rec.add("rep", fmt.Sprintf("%d", replicate+outman.replicateOffset))                   // replicate
+	rec.add("gen", fmt.Sprintf("%d", generation))                                         // generation
+	rec.add("popstat", GetStatusString(popstat))                                          // status
+	rec.add("fmale", fmt.Sprintf("%.2f", p.GetMaleFrequency()))                           // fmales
+	rec.separate()                                                                        // |
+	rec.add("fwte", fmt.Sprintf("%.2f", p.GetWithTEFrequency()))                          // fwte
+	rec.add("avw", fmt.Sprintf("%.2f", p.GetAverageFitness()))                            // w
+	rec.add("minw", fmt.Sprintf("%.2f", p.GetMinimumFitness()))                           // minw
+	rec.add("avtes", fmt.Sprintf("%.2f", p.GetAverageInsertions()))                       // avtes
+	rec.add("avpopfreq", fmt.Sprintf("%.2f", p.GetAveragePopulationFrequency()))          //  popfreq all
+	rec.add("fixed", fmt.Sprintf("%d", len(p.GetFixedInsertions())))                      // fixed insertions
+	rec.separate()                                                                        // |
+	rec.add("phase", GetPhaseString(p.GetPhase()))                                        // Phase
+	rec.add("fwpirna", fmt.Sprintf("%.2f", p.GetWithPirnaFrequency()))                    // fw piRNAs (either cluster or para)
+	rec.separate()                                                                        // |
+	rec.add("fwcli", fmt.Sprintf("%.2f", p.GetWithClusterInsertionFrequency()))           // fw cluster insertions
+	rec.add("avcli", fmt.Sprintf("%.2f", p.GetAverageClusterInsertions()))                //  number of cluster insertions
+	rec.add("fixcli", fmt.Sprintf("%d", p.GetFixedClusterInsertionCount()))               // get fixed cluster insertions
+	rec.separate()                                                                        // |
+	rec.add("fwpar_yespi", fmt.Sprintf("%.2f", p.GetWithParamutationYesPirnaFrequency())) // fw insertion into paramutable locus and piRNAs
+	rec.add("fwpar_nopi", fmt.Sprintf("%.2f", p.GetWithParamutationNoPirnaFrequency()))   // fw insertion into paramutable locus but NO piRNAs
+	rec.add("avpar", fmt.Sprintf("%.2f", p.GetAverageParaInsertions()))                   //  number of paramutable insertions
+	rec.add("fixpar", fmt.Sprintf("%d", p.GetFixedParaInsertionCount()))                  // get fixed paramutable loci
+	rec.separate()                                                                        // |
+	rec.add("piori", fmt.Sprintf("%d", p.GetPirnaOriginCount()))                          // number of independent origins of piRNAs
+	rec.add("orifreq", formatOriginFreq(p.GetPirnaOriginFrequencies(), 0.01))             // frequencies of the origins
+	rec.separate()                                                                        // |
+	rec.add("fwpi_mat", fmt.Sprintf("%.2f", p.GetWithMaternalPirnaFrequency()))           // fw maternal piRNAs
+	rec.add("fwpi_zyg", fmt.Sprintf("%.2f", p.GetWithZygoticPirnaFrequency()))            // fw solely zygotic piRNAs
+	rec.add("fdys", fmt.Sprintf("%.2f", p.GetDysgenicFrequency()))                        // fdys
+	if outman.referenceCols {
+		rec.separate()
+		pred := outman.predictions.DiploidInsertions
+		rec.add("pred_avtes", formatReference(pred))                         // predicted insertions per fly
+		rec.add("rel_avtes", formatReference(p.GetAverageInsertions()/pred)) // relative to the prediction
+	}
 	if len(outman.sampleparsed) > 0 {
-		buf.WriteString("|\t")
-		for _, sid := range outman.sampleparsed {
-			buf.WriteString(fmt.Sprintf("%s\t", sid))
+		rec.separate()
+		for i, sid := range outman.sampleparsed {
+			rec.add(fmt.Sprintf("sample%d", i+1), sid)
 		}
 	}
+	writeMainRecord(rec)
+}
 
-	// fwcli
-	// avcli
-	// fixcli
-	// |
-	// fwpara
-	// fw
-	fmt.Println(buf.String())
+func formatReference(v float64) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "NA"
+	}
+	return fmt.Sprintf("%.2f", v)
 }
 
-func getStatusString(popstat fly.PopStatus) string {
+func GetStatusString(popstat fly.PopStatus) string {
 	if popstat == fly.BASEPOP {
 		return "base"
 	} else if popstat == fly.FAIL0 {
@@ -182,13 +355,21 @@ func getStatusString(popstat fly.PopStatus) string {
 		return "fail-w"
 	} else if popstat == fly.FAILMAX {
 		return "fail-max"
+	} else if popstat == fly.FAILSTER {
+		return "fail-ster"
 	} else if popstat == fly.OK {
 		return "ok"
+	} else if popstat == fly.STOPPED {
+		return "stop"
 	} else {
 		panic("unknown population status ")
 	}
 }
-func getPhaseString(status fly.Phase) string {
+
+/*
+The short name of a phase of the invasion, e.g. rapi, trig, shot or inac
+*/
+func GetPhaseString(status fly.Phase) string {
 	if status == fly.RAPIDINVASION {
 		return "rapi"
 	} else if status == fly.TRIGGERED {
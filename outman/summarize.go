@@ -0,0 +1,123 @@
+package outman
+
+import (
+	"fmt"
+	"invade/fly"
+	"invade/io/cmdparser"
+	"invade/io/writer"
+	"strconv"
+)
+
+/*
+A replicate of a main table; the rows in the order of the generations
+*/
+type tableReplicate struct {
+	rows []tableRow
+}
+
+/*
+A row of a main table; the numeric columns and whether the population is ok or stopped (otherwise a failure, solely used for carrying)
+*/
+type tableRow struct {
+	generation int64
+	values     []float64
+	ok         bool
+}
+
+/*
+Aggregate main tables across replicates, like --file-aggregate does during the simulations;
+the replicates of all tables are combined, e.g. of runs with different --replicate-offset; written to stdout if no file is provided.
+The rows are aggregated at the recorded generations (steps), failed populations merely provide the values for carrying;
+steps and the last generation of the simulations are inferred from the tables if not provided (0)
+*/
+func SummarizeMainTables(tables []*cmdparser.MainTable, file string, failed string, quantiles string, steps int64, lastgen int64) {
+	replicates := []*tableReplicate{}
+	for _, t := range tables {
+		replicates = append(replicates, getTableReplicates(t)...)
+	}
+	if steps <= 0 {
+		steps = inferSteps(replicates)
+	}
+	if lastgen <= 0 {
+		for _, r := range replicates {
+			if last := r.rows[len(r.rows)-1].generation; last > lastgen {
+				lastgen = last
+			}
+		}
+	}
+	a := newAggregator(failed, quantiles, int64(len(replicates)), steps, lastgen)
+	writer.SetupAggregateWriter(file, getAggregateHeader(a.quantiles))
+	defer writer.CloseAggregateWriter()
+	for i, r := range replicates {
+		for _, row := range r.rows {
+			a.recordValues(row.values, int64(i), row.generation, row.ok && row.generation%steps == 0)
+		}
+		a.finishReplicate(int64(i))
+	}
+}
+
+/*
+The recorded generations of the simulations (--steps); the greatest common divisor of the generations of ok populations,
+which are solely written at the recorded generations; 1 if no population passed the base population
+*/
+func inferSteps(replicates []*tableReplicate) int64 {
+	steps := int64(0)
+	for _, r := range replicates {
+		for _, row := range r.rows {
+			if row.ok {
+				steps = gcd(steps, row.generation)
+			}
+		}
+	}
+	if steps == 0 {
+		return 1
+	}
+	return steps
+}
+
+func gcd(a int64, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+/*
+Split a main table into its replicates; the table needs the columns rep, gen, popstat and the numeric columns (see NumericColumns)
+*/
+func getTableReplicates(t *cmdparser.MainTable) []*tableReplicate {
+	repcol, gencol, statcol := t.GetColumn("rep"), t.GetColumn("gen"), t.GetColumn("popstat")
+	columns := make([]int, len(NumericColumns))
+	for i, c := range NumericColumns {
+		columns[i] = t.GetColumn(c)
+		if columns[i] < 0 {
+			panic(fmt.Sprintf("invalid main table; missing column %s", c))
+		}
+	}
+	if repcol < 0 || gencol < 0 || statcol < 0 {
+		panic("invalid main table; missing column rep, gen or popstat")
+	}
+	toret := []*tableReplicate{}
+	byrep := map[string]*tableReplicate{}
+	for _, row := range t.Rows {
+		r, ok := byrep[row[repcol]]
+		if !ok {
+			r = &tableReplicate{}
+			byrep[row[repcol]] = r
+			toret = append(toret, r)
+		}
+		gen, err := strconv.ParseInt(row[gencol], 10, 64)
+		if err != nil {
+			panic(fmt.Sprintf("invalid main table; generation %s is not a number", row[gencol]))
+		}
+		values := make([]float64, len(columns))
+		for i, c := range columns {
+			if values[i], err = strconv.ParseFloat(row[c], 64); err != nil {
+				panic(fmt.Sprintf("invalid main table; %s of generation %d is not a number (%s)", NumericColumns[i], gen, row[c]))
+			}
+		}
+		popstat := row[statcol]
+		r.rows = append(r.rows, tableRow{generation: gen, values: values, ok: popstat == GetStatusString(fly.OK) || popstat == GetStatusString(fly.STOPPED)})
+	}
+	return toret
+}
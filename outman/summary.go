@@ -0,0 +1,96 @@
+package outman
+
+import (
+	"fmt"
+	"invade/fly"
+)
+
+/*
+The milestones of a single replicate; tracked in every generation, independent of --steps;
+generations are -1 if a milestone was not reached
+*/
+type ReplicateSummary struct {
+	Replicate           int64
+	GenTriggered        int64   // first generation of the triggered phase
+	GenShotgun          int64   // first generation of the shotgun phase
+	GenInactive         int64   // first generation of the inactive phase
+	GenFirstCluster     int64   // first generation with a cluster insertion in the population
+	PeakInsertions      float64 // the highest average number of insertions per fly
+	GenPeak             int64
+	SilencingInsertions float64 // the average number of insertions per fly at the start of the shotgun phase; -1 if not reached
+	FinalGeneration     int64
+	FinalStatus         fly.PopStatus
+	GenFailure          int64
+}
+
+func newReplicateSummary(replicate int64) *ReplicateSummary {
+	return &ReplicateSummary{Replicate: replicate, GenTriggered: -1, GenShotgun: -1, GenInactive: -1, GenFirstCluster: -1,
+		PeakInsertions: -1.0, GenPeak: -1, SilencingInsertions: -1.0, FinalGeneration: -1, FinalStatus: fly.OK, GenFailure: -1}
+}
+
+/*
+Update the summary with the population of the given generation
+*/
+func (s *ReplicateSummary) update(p *fly.Population, generation int64, popstat fly.PopStatus) {
+	phase := p.GetPhase()
+	avtes := p.GetAverageInsertions()
+	if phase >= fly.TRIGGERED && s.GenTriggered < 0 {
+		s.GenTriggered = generation
+	}
+	if phase >= fly.SHOTGUN && s.GenShotgun < 0 {
+		s.GenShotgun = generation
+		s.SilencingInsertions = avtes
+	}
+	if phase >= fly.INACTIVE && s.GenInactive < 0 {
+		s.GenInactive = generation
+	}
+	if s.GenFirstCluster < 0 && p.GetAverageClusterInsertions() > 0.0 {
+		s.GenFirstCluster = generation
+	}
+	if avtes > s.PeakInsertions {
+		s.PeakInsertions = avtes
+		s.GenPeak = generation
+	}
+	s.FinalGeneration = generation
+	s.FinalStatus = popstat
+	if popstat == fly.FAIL0 || popstat == fly.FAILW || popstat == fly.FAILSEX || popstat == fly.FAILMAX || popstat == fly.FAILSTER {
+		s.GenFailure = generation
+	}
+}
+
+func getSummaryHeader() []string {
+	return []string{"rep", "gen_trig", "gen_shot", "gen_inac", "gen_firstcli", "peak_avtes", "gen_peak", "avtes_silencing", "final_gen", "final_status", "gen_fail"}
+}
+
+/*
+The columns of the summary; NA for milestones which were not reached
+*/
+func (s *ReplicateSummary) getValues() []string {
+	return []string{
+		fmt.Sprintf("%d", s.Replicate),
+		formatMilestone(s.GenTriggered),
+		formatMilestone(s.GenShotgun),
+		formatMilestone(s.GenInactive),
+		formatMilestone(s.GenFirstCluster),
+		fmt.Sprintf("%.2f", s.PeakInsertions),
+		formatMilestone(s.GenPeak),
+		formatMilestoneFloat(s.SilencingInsertions),
+		fmt.Sprintf("%d", s.FinalGeneration),
+		GetStatusString(s.FinalStatus),
+		formatMilestone(s.GenFailure),
+	}
+}
+
+func formatMilestone(gen int64) string {
+	if gen < 0 {
+		return "NA"
+	}
+	return fmt.Sprintf("%d", gen)
+}
+
+func formatMilestoneFloat(v float64) string {
+	if v < 0.0 {
+		return "NA"
+	}
+	return fmt.Sprintf("%.2f", v)
+}
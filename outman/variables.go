@@ -0,0 +1,55 @@
+package outman
+
+import (
+	"invade/fly"
+)
+
+/*
+The numeric columns of the main table, in the order of the table
+*/
+var NumericColumns = []string{"fmale", "fwte", "avw", "minw", "avtes", "avpopfreq", "fixed", "fwpirna",
+	"fwcli", "avcli", "fixcli", "fwpar_yespi", "fwpar_nopi", "avpar", "fixpar", "piori", "fwpi_mat", "fwpi_zyg", "fdys"}
+
+/*
+The numeric columns of a population (see NumericColumns)
+*/
+var numericVariables = map[string]func(p *fly.Population) float64{
+	"fmale":       func(p *fly.Population) float64 { return p.GetMaleFrequency() },
+	"fwte":        func(p *fly.Population) float64 { return p.GetWithTEFrequency() },
+	"avw":         func(p *fly.Population) float64 { return p.GetAverageFitness() },
+	"minw":        func(p *fly.Population) float64 { return p.GetMinimumFitness() },
+	"avtes":       func(p *fly.Population) float64 { return p.GetAverageInsertions() },
+	"avpopfreq":   func(p *fly.Population) float64 { return p.GetAveragePopulationFrequency() },
+	"fixed":       func(p *fly.Population) float64 { return float64(len(p.GetFixedInsertions())) },
+	"fwpirna":     func(p *fly.Population) float64 { return p.GetWithPirnaFrequency() },
+	"fwpi_mat":    func(p *fly.Population) float64 { return p.GetWithMaternalPirnaFrequency() },
+	"fwpi_zyg":    func(p *fly.Population) float64 { return p.GetWithZygoticPirnaFrequency() },
+	"fdys":        func(p *fly.Population) float64 { return p.GetDysgenicFrequency() },
+	"fwcli":       func(p *fly.Population) float64 { return p.GetWithClusterInsertionFrequency() },
+	"avcli":       func(p *fly.Population) float64 { return p.GetAverageClusterInsertions() },
+	"fixcli":      func(p *fly.Population) float64 { return float64(p.GetFixedClusterInsertionCount()) },
+	"fwpar_yespi": func(p *fly.Population) float64 { return p.GetWithParamutationYesPirnaFrequency() },
+	"fwpar_nopi":  func(p *fly.Population) float64 { return p.GetWithParamutationNoPirnaFrequency() },
+	"avpar":       func(p *fly.Population) float64 { return p.GetAverageParaInsertions() },
+	"fixpar":      func(p *fly.Population) float64 { return float64(p.GetFixedParaInsertionCount()) },
+	"piori":       func(p *fly.Population) float64 { return float64(p.GetPirnaOriginCount()) },
+}
+
+/*
+The function of a numeric column of the main table, e.g. for the stop conditions; false if the column is unknown
+*/
+func GetVariable(name string) (func(p *fly.Population) float64, bool) {
+	f, ok := numericVariables[name]
+	return f, ok
+}
+
+/*
+Get the numeric columns of the main table for a population
+*/
+func GetNumericValues(p *fly.Population) []float64 {
+	values := make([]float64, len(NumericColumns))
+	for i, c := range NumericColumns {
+		values[i] = numericVariables[c](p)
+	}
+	return values
+}
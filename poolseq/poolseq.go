@@ -0,0 +1,138 @@
+/*
+Simulated pool-seq sampling of TE insertions (e.g. PoPoolationTE2); a sample of flies is pooled and sequenced,
+the population frequency of the insertions is estimated from the reads
+*/
+package poolseq
+
+import (
+	"invade/fly"
+	"invade/util"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+/*
+The result of sequencing a pool for a single insertion site
+*/
+type PoolSite struct {
+	Site       int64
+	TrueFreq   float64 // population frequency of the insertion (census)
+	SampleFreq float64 // frequency of the insertion in the sampled flies
+	Coverage   int64   // number of reads covering the site
+	Support    int64   // number of reads supporting the insertion
+}
+
+/*
+Estimated population frequency of the insertion, i.e. the fraction of the reads supporting the insertion; NaN if the site is not covered
+*/
+func (ps PoolSite) EstimatedFreq() float64 {
+	if ps.Coverage == 0 {
+		return math.NaN()
+	}
+	return float64(ps.Support) / float64(ps.Coverage)
+}
+
+type Sequencer struct {
+	flies      int64      // number of flies in the pool
+	coverage   float64    // average coverage
+	dispersion float64    // size parameter of the negative binomial coverage; 0 = Poisson
+	rng        *rand.Rand // separate random number generator; sequencing does not change the simulation
+}
+
+var sequencer Sequencer
+
+/*
+Setup the pool-seq sampling; the coverage is Poisson distributed (dispersion = 0.0) or negative binomial distributed with the given size parameter (dispersion > 0.0);
+smaller values of the size parameter yield a larger variance of the coverage (var = c + c^2/dispersion)
+*/
+func SetupSequencer(flies int64, coverage float64, dispersion float64, seed int64) {
+	if flies < 1 {
+		panic("invalid number of flies in the pool; must be larger than 0")
+	}
+	if coverage <= 0.0 {
+		panic("invalid coverage of the pool; must be larger than 0.0")
+	}
+	if dispersion < 0.0 {
+		panic("invalid dispersion of the coverage; must be larger or equal to 0.0")
+	}
+	sequencer = Sequencer{flies: flies, coverage: coverage, dispersion: dispersion, rng: rand.New(rand.NewSource(seed))}
+}
+
+/*
+Sequence a pool of flies of the population; i) draw a random sample of flies ii) draw the coverage of each site iii) draw the reads supporting the insertion.
+All insertion sites segregating in the population are reported (sorted by position), including sites that are absent in the sample
+*/
+func SequencePool(p *fly.Population) []PoolSite {
+	sample := sequencer.sampleFlies(p.Flies)
+	sampleCount := make(map[int64]int64)
+	for _, f := range sample {
+		for _, s := range f.Hap1 {
+			sampleCount[s]++
+		}
+		for _, s := range f.Hap2 {
+			sampleCount[s]++
+		}
+	}
+	truefreq := p.GetMHPPopulationFrequency()
+	sites := make([]int64, 0, len(truefreq))
+	for s := range truefreq {
+		sites = append(sites, s)
+	}
+	sort.Slice(sites, func(i, j int) bool { return sites[i] < sites[j] })
+
+	toret := make([]PoolSite, 0, len(sites))
+	for _, s := range sites {
+		samplefreq := float64(sampleCount[s]) / float64(2*len(sample))
+		coverage := sequencer.getCoverage()
+		support := sequencer.getSupport(coverage, samplefreq)
+		toret = append(toret, PoolSite{Site: s, TrueFreq: truefreq[s], SampleFreq: samplefreq, Coverage: coverage, Support: support})
+	}
+	return toret
+}
+
+/*
+Random sample of flies without replacement; all flies if the pool is larger than the population
+*/
+func (sq *Sequencer) sampleFlies(flies []fly.Fly) []fly.Fly {
+	if sq.flies >= int64(len(flies)) {
+		return flies
+	}
+	perm := sq.rng.Perm(len(flies))
+	toret := make([]fly.Fly, sq.flies)
+	for i := range toret {
+		toret[i] = flies[perm[i]]
+	}
+	return toret
+}
+
+/*
+Coverage of a site; Poisson or negative binomial (gamma-Poisson mixture)
+*/
+func (sq *Sequencer) getCoverage() int64 {
+	lambda := sq.coverage
+	if sq.dispersion > 0.0 {
+		lambda = sq.gamma(sq.dispersion, sq.coverage/sq.dispersion)
+	}
+	return util.PoissonFrom(sq.rng.Float64, lambda)
+}
+
+/*
+Number of reads supporting the insertion; binomial, each read is derived from a random haplotype of the pool
+*/
+func (sq *Sequencer) getSupport(coverage int64, samplefreq float64) int64 {
+	var support int64 = 0
+	for i := int64(0); i < coverage; i++ {
+		if sq.rng.Float64() < samplefreq {
+			support++
+		}
+	}
+	return support
+}
+
+/*
+Gamma distributed random numbers from the random number generator of the sequencer
+*/
+func (sq *Sequencer) gamma(shape float64, scale float64) float64 {
+	return util.GammaFrom(sq.rng.Float64, sq.rng.NormFloat64, shape) * scale
+}
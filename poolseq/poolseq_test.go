@@ -0,0 +1,72 @@
+package poolseq
+
+import (
+	"invade/env"
+	"invade/fly"
+	"math"
+	"testing"
+)
+
+func TestSequencePool(test *testing.T) {
+	env.SetupEnvironment([]int64{100, 100}, []int64{0, 0}, []int64{0, 0}, []bool{false}, []bool{false}, []float64{0, 0}, 0.1, 1000.0)
+	fly.SetupFitness(0.0, 1.0, false, false)
+	flies := []fly.Fly{
+		*fly.NewFly([]int64{10, 20}, []int64{10}, fly.MALE, 0),
+		*fly.NewFly([]int64{10}, []int64{}, fly.FEMALE, 0),
+	}
+	pop := fly.InitializePopulation(flies)
+	SetupSequencer(10, 1000.0, 0.0, 1) // pool is larger than the population -> all flies
+	got := SequencePool(pop)
+	var tests = []struct {
+		site int64
+		freq float64
+	}{
+		{site: 10, freq: 0.75},
+		{site: 20, freq: 0.25},
+	}
+	if len(got) != len(tests) {
+		test.Fatalf("Incorrect number of sites; got %d, want %d", len(got), len(tests))
+	}
+	for i, t := range tests {
+		ps := got[i]
+		if ps.Site != t.site || ps.TrueFreq != t.freq || ps.SampleFreq != t.freq {
+			test.Errorf("Incorrect pool site; got %v, want site %d with frequency %f", ps, t.site, t.freq)
+		}
+		if math.Abs(ps.EstimatedFreq()-t.freq) > 0.1 {
+			test.Errorf("Incorrect estimated frequency of site %d; got %f, want %f", t.site, ps.EstimatedFreq(), t.freq)
+		}
+	}
+	if !math.IsNaN(PoolSite{}.EstimatedFreq()) {
+		test.Errorf("Estimated frequency of an uncovered site must be NaN")
+	}
+}
+
+func TestStochasticCoverage(test *testing.T) {
+	var tests = []struct {
+		coverage   float64
+		dispersion float64
+		variance   float64
+	}{
+		{coverage: 50.0, dispersion: 0.0, variance: 50.0},
+		{coverage: 50.0, dispersion: 5.0, variance: 550.0},
+		{coverage: 20.0, dispersion: 0.5, variance: 820.0},
+	}
+	for _, t := range tests {
+		SetupSequencer(1, t.coverage, t.dispersion, 42)
+		n := 100000
+		var sum, sos float64
+		for i := 0; i < n; i++ {
+			c := float64(sequencer.getCoverage())
+			sum += c
+			sos += c * c
+		}
+		mean := sum / float64(n)
+		variance := sos/float64(n) - mean*mean
+		if math.Abs(mean-t.coverage) > 0.05*t.coverage {
+			test.Errorf("Incorrect mean coverage; got %f, want %f", mean, t.coverage)
+		}
+		if math.Abs(variance-t.variance) > 0.1*t.variance {
+			test.Errorf("Incorrect variance of the coverage; got %f, want %f", variance, t.variance)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Population genetic summary statistics of TE insertions (site frequency spectrum, theta estimators, Tajima's D, heterozygosity);
+the haplotypes of the entire population are the sample
+*/
+package popgen
+
+import (
+	"invade/env"
+	"invade/fly"
+	"math"
+)
+
+/*
+The categories of insertions for which statistics are computed; "all" comprises all insertions, the others are the categories of env.ScoreInsertion
+*/
+var Categories = []string{"all", "clu", "ref", "par", "tri", "noe"}
+
+type SiteStatistics struct {
+	Segregating    int64   // number of segregating insertion sites; fixed insertions are excluded
+	ThetaW         float64 // Watterson's theta; per locus (not per site)
+	ThetaPi        float64 // Tajima's theta, i.e. average number of pairwise differences
+	TajimaD        float64 // Tajima's D; NaN without segregating sites
+	Heterozygosity float64 // expected heterozygosity (2pq) averaged over the segregating sites; NaN without segregating sites
+}
+
+/*
+Compute the statistics for each category of insertions (see Categories)
+*/
+func GetStatistics(p *fly.Population) map[string]SiteStatistics {
+	counts := make(map[int64]int64)
+	for _, f := range p.Flies {
+		for _, s := range f.Hap1 {
+			counts[s]++
+		}
+		for _, s := range f.Hap2 {
+			counts[s]++
+		}
+	}
+	bycategory := make(map[string][]int64)
+	for s, c := range counts {
+		cat := env.ScoreInsertion(s)
+		bycategory[cat] = append(bycategory[cat], c)
+		bycategory["all"] = append(bycategory["all"], c)
+	}
+	n := 2 * p.Size()
+	toret := make(map[string]SiteStatistics)
+	for _, cat := range Categories {
+		toret[cat] = ComputeStatistics(bycategory[cat], n)
+	}
+	return toret
+}
+
+/*
+Compute the statistics given the number of haplotypes carrying an insertion (derived allele count) for each site and the number of haplotypes (n)
+*/
+func ComputeStatistics(counts []int64, n int64) SiteStatistics {
+	if n < 2 {
+		panic("invalid number of haplotypes; must be larger than 1")
+	}
+	var seg int64 = 0
+	pi := 0.0
+	het := 0.0
+	nf := float64(n)
+	for _, c := range counts {
+		if c <= 0 || c >= n {
+			continue
+		}
+		seg++
+		cf := float64(c)
+		pi += 2.0 * cf * (nf - cf) / (nf * (nf - 1.0))
+		freq := cf / nf
+		het += 2.0 * freq * (1.0 - freq)
+	}
+	a1, a2 := 0.0, 0.0
+	for i := 1.0; i < nf; i++ {
+		a1 += 1.0 / i
+		a2 += 1.0 / (i * i)
+	}
+	if seg == 0 {
+		return SiteStatistics{Segregating: 0, ThetaW: 0.0, ThetaPi: 0.0, TajimaD: math.NaN(), Heterozygosity: math.NaN()}
+	}
+	segf := float64(seg)
+	return SiteStatistics{Segregating: seg, ThetaW: segf / a1, ThetaPi: pi, TajimaD: tajimaD(pi, segf, nf, a1, a2), Heterozygosity: het / segf}
+}
+
+/*
+Tajima's D (Tajima 1989)
+*/
+func tajimaD(pi float64, seg float64, n float64, a1 float64, a2 float64) float64 {
+	b1 := (n + 1.0) / (3.0 * (n - 1.0))
+	b2 := 2.0 * (n*n + n + 3.0) / (9.0 * n * (n - 1.0))
+	c1 := b1 - 1.0/a1
+	c2 := b2 - (n+2.0)/(a1*n) + a2/(a1*a1)
+	e1 := c1 / a1
+	e2 := c2 / (a1*a1 + a2)
+	variance := e1*seg + e2*seg*(seg-1.0)
+	if variance <= 0.0 {
+		return math.NaN()
+	}
+	return (pi - seg/a1) / math.Sqrt(variance)
+}
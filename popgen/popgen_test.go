@@ -0,0 +1,81 @@
+package popgen
+
+import (
+	"invade/env"
+	"invade/fly"
+	"math"
+	"testing"
+)
+
+func TestComputeStatistics(test *testing.T) {
+	var tests = []struct {
+		counts []int64
+		n      int64
+		seg    int64
+		thetaw float64
+		thetap float64
+		tajd   float64
+		het    float64
+	}{
+		{counts: []int64{1, 2, 3}, n: 4, seg: 3, thetaw: 1.636364, thetap: 1.666667, tajd: 0.167656, het: 0.416667},
+		{counts: []int64{1, 1, 1, 1}, n: 10, seg: 4, thetaw: 1.413943, thetap: 0.8, tajd: -1.667061, het: 0.18},
+		{counts: []int64{0, 1, 1, 1, 1, 10}, n: 10, seg: 4, thetaw: 1.413943, thetap: 0.8, tajd: -1.667061, het: 0.18}, // absent and fixed sites are ignored
+	}
+	for _, t := range tests {
+		got := ComputeStatistics(t.counts, t.n)
+		if got.Segregating != t.seg || math.Abs(got.ThetaW-t.thetaw) > 0.00001 || math.Abs(got.ThetaPi-t.thetap) > 0.00001 ||
+			math.Abs(got.TajimaD-t.tajd) > 0.00001 || math.Abs(got.Heterozygosity-t.het) > 0.00001 {
+			test.Errorf("Incorrect statistics for counts %v; got %+v", t.counts, got)
+		}
+	}
+	got := ComputeStatistics([]int64{}, 10)
+	if got.Segregating != 0 || got.ThetaW != 0.0 || !math.IsNaN(got.TajimaD) || !math.IsNaN(got.Heterozygosity) {
+		test.Errorf("Incorrect statistics without segregating sites; got %+v", got)
+	}
+}
+
+func TestGetStatistics(test *testing.T) {
+	env.SetupEnvironment([]int64{100, 100}, []int64{10, 10}, []int64{0, 0}, []bool{false}, []bool{false}, []float64{0, 0}, 0.1, 1000.0)
+	fly.SetupFitness(0.0, 1.0, false, false)
+	flies := []fly.Fly{
+		*fly.NewFly([]int64{5, 50}, []int64{50}, fly.MALE, 0),
+		*fly.NewFly([]int64{50}, []int64{150}, fly.FEMALE, 0),
+	}
+	stats := GetStatistics(fly.InitializePopulation(flies))
+	var tests = []struct {
+		category string
+		seg      int64
+	}{
+		{"all", 3},
+		{"clu", 1},
+		{"noe", 2},
+		{"ref", 0},
+	}
+	for _, t := range tests {
+		if got := stats[t.category].Segregating; got != t.seg {
+			test.Errorf("Incorrect number of segregating sites for %s; got %d, want %d", t.category, got, t.seg)
+		}
+	}
+}
+
+func TestGetSummaryStatistics(test *testing.T) {
+	env.SetupEnvironment([]int64{100, 100}, []int64{10, 10}, []int64{0, 0}, []bool{false}, []bool{false}, []float64{0, 0}, 0.1, 1000.0)
+	fly.SetupFitness(0.0, 1.0, false, false)
+	flies := []fly.Fly{
+		*fly.NewFly([]int64{5, 50}, []int64{5, 60}, fly.FEMALE, 0),
+		*fly.NewFly([]int64{5}, []int64{5, 50}, fly.MALE, 0),
+	}
+	p := fly.InitializePopulation(flies)
+	// site 5 fixed (4/4), site 50 at 2/4 (bin 5), site 60 at 1/4 (bin 3)
+	want := map[string]float64{"avtes": 3.5, "avcli": 2.0, "fcli": 4.0 / 7.0, "fixed": 1, "sites": 3, "sfs3": 1.0 / 3.0, "sfs5": 1.0 / 3.0, "sfs10": 1.0 / 3.0, "sfs1": 0.0}
+	names := GetSummaryStatisticNames()
+	got := GetSummaryStatistics(p)
+	if len(got) != len(names) {
+		test.Fatalf("Incorrect number of summary statistics; want %d, got %d", len(names), len(got))
+	}
+	for i, name := range names {
+		if w, ok := want[name]; ok && math.Abs(got[i]-w) > 0.00001 {
+			test.Errorf("Incorrect summary statistic %s; want %f, got %f", name, w, got[i])
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package popgen
+
+import (
+	"fmt"
+	"invade/env"
+	"invade/fly"
+)
+
+/*
+Number of bins of the site frequency spectrum of the summary statistics; bin k comprises the sites with a population frequency in ((k-1)/bins, k/bins]
+*/
+const SFSBINS int = 10
+
+/*
+The names of the summary statistics of a population, e.g. for approximate Bayesian computation;
+copy number (avtes), cluster insertions (avcli), fraction of cluster insertions (fcli), fixed insertions (fixed), insertion sites (sites)
+and the fraction of the sites in each bin of the site frequency spectrum (sfs1..sfs10)
+*/
+func GetSummaryStatisticNames() []string {
+	names := []string{"avtes", "avcli", "fcli", "fixed", "sites"}
+	for k := 1; k <= SFSBINS; k++ {
+		names = append(names, fmt.Sprintf("sfs%d", k))
+	}
+	return names
+}
+
+/*
+Compute the summary statistics of a population, in the order of GetSummaryStatisticNames
+*/
+func GetSummaryStatistics(p *fly.Population) []float64 {
+	counts := make(map[int64]int64)
+	var total, cluster int64
+	for _, f := range p.Flies {
+		for _, hap := range [][]int64{f.Hap1, f.Hap2} {
+			for _, s := range hap {
+				counts[s]++
+				total++
+				if env.IsClusterInsertion(s) {
+					cluster++
+				}
+			}
+		}
+	}
+	n := 2 * p.Size()
+	var fixed int64
+	sfs := make([]float64, SFSBINS)
+	for _, c := range counts {
+		if c == n {
+			fixed++
+		}
+		bin := int((c*int64(SFSBINS) - 1) / n) // frequencies of exactly k/bins fall into bin k
+		sfs[bin]++
+	}
+	fcli := 0.0
+	if total > 0 {
+		fcli = float64(cluster) / float64(total)
+	}
+	stats := []float64{float64(total) / float64(p.Size()), float64(cluster) / float64(p.Size()), fcli, float64(fixed), float64(len(counts))}
+	for _, b := range sfs {
+		if len(counts) > 0 {
+			b /= float64(len(counts))
+		}
+		stats = append(stats, b)
+	}
+	return stats
+}
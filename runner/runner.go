@@ -0,0 +1,126 @@
+/*
+Helpers of the subcommands that run simulations as separate invade processes (sweep, abc); splitting the arguments
+and running the processes
+*/
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+/*
+Split the arguments into the given parameters (name -> is boolean), e.g. of the sweep, and the parameters of the simulations
+*/
+func SplitArgs(args []string, flags map[string]bool) (map[string]string, []string) {
+	opts := make(map[string]string)
+	passthrough := []string{}
+	for i := 0; i < len(args); i++ {
+		name := strings.TrimLeft(args[i], "-")
+		value := ""
+		hasValue := false
+		if strings.Contains(name, "=") {
+			tmp := strings.SplitN(name, "=", 2)
+			name, value, hasValue = tmp[0], tmp[1], true
+		}
+		if name == "h" || name == "help" {
+			opts["help"] = "true"
+			continue
+		}
+		isBool, ok := flags[name]
+		if !strings.HasPrefix(args[i], "-") || !ok {
+			passthrough = append(passthrough, args[i])
+			continue
+		}
+		if isBool {
+			if !hasValue {
+				value = "true"
+			}
+		} else if !hasValue {
+			if i+1 >= len(args) {
+				panic(fmt.Sprintf("missing value of parameter %s", args[i]))
+			}
+			i++
+			value = args[i]
+		}
+		opts[name] = value
+	}
+	return opts, passthrough
+}
+
+/*
+Make the output files of a simulation (the parameters starting with 'file-') specific to a run, as parallel runs would overwrite each other's files;
+the tag is inserted before the extension of the file, e.g. --file-mhp out/mhp.txt.gz becomes --file-mhp out/mhp.run3.txt.gz with the tag 'run3'
+*/
+func TagOutputFiles(args []string, tag string) []string {
+	toret := append([]string{}, args...)
+	for i := 0; i < len(toret); i++ {
+		if !strings.HasPrefix(toret[i], "-") {
+			continue
+		}
+		name := strings.TrimLeft(toret[i], "-")
+		if !strings.HasPrefix(name, "file-") {
+			continue
+		}
+		if strings.Contains(name, "=") {
+			tmp := strings.SplitN(toret[i], "=", 2)
+			toret[i] = tmp[0] + "=" + tagFile(tmp[1], tag)
+		} else if i+1 < len(toret) {
+			i++
+			toret[i] = tagFile(toret[i], tag)
+		}
+	}
+	return toret
+}
+
+func tagFile(file string, tag string) string {
+	if file == "" || file == os.DevNull {
+		return file
+	}
+	dir, base := filepath.Split(file)
+	if k := strings.Index(base, "."); k > 0 {
+		return dir + base[:k] + "." + tag + base[k:]
+	}
+	return file + "." + tag
+}
+
+/*
+Run a simulation as separate process of invade; returns the standard output, or the panic message if the simulation failed
+*/
+func RunSimulation(exe string, args []string) (string, error) {
+	cmd := exec.Command(exe, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%v; %s", err, getPanicMessage(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func getPanicMessage(stderr string) string {
+	for _, line := range strings.Split(stderr, "\n") {
+		if strings.HasPrefix(line, "panic:") {
+			return line
+		}
+	}
+	lines := SplitLines(stderr)
+	if len(lines) > 0 {
+		return lines[len(lines)-1]
+	}
+	return ""
+}
+
+func SplitLines(s string) []string {
+	toret := []string{}
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(line) != "" {
+			toret = append(toret, line)
+		}
+	}
+	return toret
+}
@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	flags := map[string]bool{"grid": false, "threads": false, "summaries-only": true}
+	var tests = []struct {
+		args            []string
+		wantopts        map[string]string
+		wantpassthrough string
+	}{
+		{args: []string{"--grid", "u=0.1", "--N", "100"}, wantopts: map[string]string{"grid": "u=0.1"}, wantpassthrough: "--N 100"},
+		{args: []string{"--threads=4", "--summaries-only", "--silent"}, wantopts: map[string]string{"threads": "4", "summaries-only": "true"}, wantpassthrough: "--silent"},
+		{args: []string{"--summaries-only=false", "-h"}, wantopts: map[string]string{"summaries-only": "false", "help": "true"}, wantpassthrough: ""},
+	}
+	for _, test := range tests {
+		opts, passthrough := SplitArgs(test.args, flags)
+		if len(opts) != len(test.wantopts) {
+			t.Errorf("Incorrect parameters of %v; got %v", test.args, opts)
+		}
+		for k, v := range test.wantopts {
+			if opts[k] != v {
+				t.Errorf("Incorrect value of %s in %v; want %s, got %s", k, test.args, v, opts[k])
+			}
+		}
+		if got := strings.Join(passthrough, " "); got != test.wantpassthrough {
+			t.Errorf("Incorrect passthrough of %v; want %s, got %s", test.args, test.wantpassthrough, got)
+		}
+	}
+}
+
+func TestGetPanicMessage(t *testing.T) {
+	var tests = []struct {
+		stderr string
+		want   string
+	}{
+		{stderr: "Invade: 10:00:00 start\npanic: invalid genome\n\ngoroutine 1 [running]:\n", want: "panic: invalid genome"},
+		{stderr: "first\nlast\n\n", want: "last"},
+		{stderr: "", want: ""},
+	}
+	for _, test := range tests {
+		if got := getPanicMessage(test.stderr); got != test.want {
+			t.Errorf("Incorrect panic message; want %s, got %s", test.want, got)
+		}
+	}
+}
+
+func TestTagOutputFiles(t *testing.T) {
+	var tests = []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"--N", "100", "--file-mhp", "mhp.txt"}, want: "--N 100 --file-mhp mhp.run3.txt"},
+		{args: []string{"--file-vcf=out/invade.vcf.gz", "--silent"}, want: "--file-vcf=out/invade.run3.vcf.gz --silent"},
+		{args: []string{"-file-ts", "res/genealogy", "--basepop", "base.txt"}, want: "-file-ts res/genealogy.run3 --basepop base.txt"},
+		{args: []string{"--file-main", os.DevNull}, want: "--file-main " + os.DevNull},
+	}
+	for _, test := range tests {
+		if got := strings.Join(TagOutputFiles(test.args, "run3"), " "); got != test.want {
+			t.Errorf("Incorrect output files of %v; want %s, got %s", test.args, test.want, got)
+		}
+	}
+}
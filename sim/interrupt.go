@@ -0,0 +1,19 @@
+package sim
+
+import (
+	"sync/atomic"
+)
+
+/*
+Set by an interrupt (e.g. SIGINT, SIGTERM); the simulations end after the current generation.
+Solely the flag is set asynchronously, the output is closed after the simulations returned
+*/
+var interrupted int32
+
+func Interrupt() {
+	atomic.StoreInt32(&interrupted, 1)
+}
+
+func IsInterrupted() bool {
+	return atomic.LoadInt32(&interrupted) == 1
+}
@@ -6,28 +6,84 @@ import (
 	"invade/outman"
 )
 
+/*
+A base population from a VCF of TE insertions; if set, the VCF is used instead of --basepop
+*/
+type BasePopVCF struct {
+	file     string
+	resample bool // draw the flies randomly from the samples of the VCF
+}
+
+var basepopvcf BasePopVCF
+
+func SetupBasePopVCF(file string, resample bool) {
+	basepopvcf = BasePopVCF{file: file, resample: resample}
+}
+
+/*
+A base population with standing TE variation; the insertion sites have frequencies drawn from a frequency spectrum;
+if set, it is used instead of --basepop
+*/
+type BasePopSpectrum struct {
+	sites    int64
+	spectrum string
+	cluster  string // frequencies of the cluster insertions
+}
+
+var basepopspectrum BasePopSpectrum
+
+func SetupBasePopSpectrum(sites int64, spectrum string, cluster string) {
+	basepopspectrum = BasePopSpectrum{sites: sites, spectrum: spectrum, cluster: cluster}
+}
+
+/*
+The base population; from a VCF, a frequency spectrum or --basepop (mutually exclusive, see cmdparser.ParseCommandLine)
+*/
+func LoadBasePopulation(basepop string, popsize int64) *fly.Population {
+	if basepopvcf.file != "" {
+		return cmdparser.ParseBasePopVCF(basepopvcf.file, popsize, basepopvcf.resample)
+	}
+	if basepopspectrum.sites > 0 || basepopspectrum.cluster != "" {
+		return cmdparser.ParseBasePopSpectrum(basepopspectrum.sites, basepopspectrum.spectrum, basepopspectrum.cluster, popsize)
+	}
+	return cmdparser.ParseBasePop(basepop, popsize)
+}
+
 /*
  perform the simulations;
- multiple replicates and generations
+ multiple replicates and generations; an interrupt ends the simulations after the current generation (see Interrupt)
 */
-func SimulateInvasions(basepop string, popsize int64, replicates int64, generation int64) {
-	for k := int64(0); k < replicates; k++ {
-		pop := cmdparser.ParseBasePop(basepop, popsize)
+func SimulateInvasions(first *fly.Population, basepop string, popsize int64, replicates int64, generation int64) {
+	for k := int64(0); k < replicates && !IsInterrupted(); k++ {
+		pop := first // the base population of the first replicate is loaded beforehand, e.g. for the analytical predictions
+		if k > 0 {
+			pop = LoadBasePopulation(basepop, popsize)
+		}
+		stopcondition.reset()
 		status := pop.GetStatus()
 		outman.RecordPopulation(pop, k, 0, status)
 		if status != fly.OK {
+			outman.FinishReplicate(pop, k)
 			continue // skip simulation for invalid base populations
 		}
 
 		for i := int64(1); i <= generation; i++ { // needs to start 1; 0 is the base population
 			pop = pop.GetNextGeneration() // Fuck multithreading! we loose reproducibitilty with the seeds!
 			status := pop.GetStatus()
+			if status == fly.OK && stopcondition.isStopped(pop, k, i) {
+				status = fly.STOPPED
+			}
 			outman.RecordPopulation(pop, k, i, status)
 
 			// if the status is not ok abort!
 			if status != fly.OK {
 				break
 			}
+			if IsInterrupted() {
+				return // the replicate is incomplete; neither the genealogy nor the summary are written
+			}
 		}
+		outman.RecordGenealogy(pop, k)
+		outman.FinishReplicate(pop, k)
 	}
 }
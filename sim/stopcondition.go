@@ -0,0 +1,253 @@
+package sim
+
+import (
+	"fmt"
+	"invade/fly"
+	"invade/outman"
+	"invade/util"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/*
+A user-defined condition for stopping a replicate early, e.g. "phase==inac for 100", "fwpirna>0.99" or "stationary(avtes,200)";
+terms may be combined with && and || (&& binds stronger);
+the simulation continues for 'extra' generations after the condition is met
+*/
+type StopCondition struct {
+	expression string
+	clauses    [][]*stopTerm // a disjunction of conjunctions
+	extra      int64
+	metAt      int64 // the generation at which the condition was met; -1 if not met yet
+}
+
+/*
+A single term of a stop condition;
+either a comparison of a variable with a value (e.g. fwpirna>0.99) or a stationarity test of a variable over a sliding window (e.g. stationary(avtes,200,0.05));
+optionally the term needs to hold for a number of consecutive generations (e.g. "phase==inac for 100")
+*/
+type stopTerm struct {
+	variable    string
+	get         func(p *fly.Population) float64
+	operator    string
+	value       float64
+	stationary  bool
+	window      int64
+	tolerance   float64 // maximum relative change over the window
+	duration    int64   // number of consecutive generations
+	history     []float64
+	consecutive int64
+}
+
+/*
+The variables of the stop conditions in addition to the numeric columns of the main output (see outman.GetVariable)
+*/
+var stopVariables = map[string]func(p *fly.Population) float64{
+	"gen":   func(p *fly.Population) float64 { return float64(p.GetGeneration()) },
+	"phase": func(p *fly.Population) float64 { return float64(p.GetPhase()) },
+}
+
+var comparisonTerm = regexp.MustCompile(`^(\w+)\s*(==|!=|>=|<=|>|<)\s*([\w.+-]+)(?:\s+for\s+(\d+))?$`)
+var stationaryTerm = regexp.MustCompile(`^stationary\(\s*(\w+)\s*,\s*(\d+)\s*(?:,\s*([\d.eE+-]+)\s*)?\)(?:\s+for\s+(\d+))?$`)
+
+var stopcondition StopCondition
+
+func SetupStopCondition(expression string, extra int64) {
+	if extra < 0 {
+		panic(fmt.Sprintf("invalid number of generations after the stop condition %d; must be larger or equal to 0", extra))
+	}
+	stopcondition = StopCondition{expression: expression, clauses: parseStopCondition(expression), extra: extra, metAt: -1}
+}
+
+/*
+Parse a stop condition; an empty expression yields no stop condition
+*/
+func parseStopCondition(expression string) [][]*stopTerm {
+	clauses := [][]*stopTerm{}
+	if strings.TrimSpace(expression) == "" {
+		return clauses
+	}
+	for _, disj := range strings.Split(expression, "||") {
+		clause := []*stopTerm{}
+		for _, conj := range strings.Split(disj, "&&") {
+			clause = append(clause, parseStopTerm(strings.TrimSpace(conj)))
+		}
+		clauses = append(clauses, clause)
+	}
+	return clauses
+}
+
+func parseStopTerm(s string) *stopTerm {
+	if m := stationaryTerm.FindStringSubmatch(s); m != nil {
+		get := getStopVariable(m[1], s)
+		window, _ := strconv.ParseInt(m[2], 10, 64)
+		if window < 2 {
+			panic(fmt.Sprintf("invalid stop condition %s; the window must be at least 2 generations", s))
+		}
+		tolerance := 0.05
+		if m[3] != "" {
+			var err error
+			if tolerance, err = strconv.ParseFloat(m[3], 64); err != nil || tolerance < 0.0 {
+				panic(fmt.Sprintf("invalid stop condition %s; the tolerance must be a positive number", s))
+			}
+		}
+		return &stopTerm{variable: m[1], get: get, stationary: true, window: window, tolerance: tolerance, duration: parseDuration(m[4])}
+	}
+	if m := comparisonTerm.FindStringSubmatch(s); m != nil {
+		get := getStopVariable(m[1], s)
+		var value float64
+		if m[1] == "phase" {
+			value = float64(parsePhase(m[3]))
+		} else {
+			var err error
+			if value, err = strconv.ParseFloat(m[3], 64); err != nil {
+				panic(fmt.Sprintf("invalid stop condition %s; %s is not a number", s, m[3]))
+			}
+		}
+		return &stopTerm{variable: m[1], get: get, operator: m[2], value: value, duration: parseDuration(m[4])}
+	}
+	panic(fmt.Sprintf("invalid stop condition '%s'; e.g. 'phase==inac for 100', 'fwpirna>0.99' or 'stationary(avtes,200,0.05)'", s))
+}
+
+/*
+The function of a variable of the stop conditions; named like the columns of the main output
+*/
+func getStopVariable(variable string, s string) func(p *fly.Population) float64 {
+	if f, ok := stopVariables[variable]; ok {
+		return f
+	}
+	if f, ok := outman.GetVariable(variable); ok {
+		return f
+	}
+	panic(fmt.Sprintf("invalid stop condition %s; unknown variable %s", s, variable))
+}
+
+func parseDuration(s string) int64 {
+	if s == "" {
+		return 1
+	}
+	duration, _ := strconv.ParseInt(s, 10, 64)
+	if duration < 1 {
+		panic(fmt.Sprintf("invalid duration of stop condition %s; must be at least one generation", s))
+	}
+	return duration
+}
+
+func parsePhase(s string) fly.Phase {
+	for _, phase := range []fly.Phase{fly.RAPIDINVASION, fly.TRIGGERED, fly.SHOTGUN, fly.INACTIVE} {
+		if outman.GetPhaseString(phase) == s {
+			return phase
+		}
+	}
+	panic(fmt.Sprintf("invalid phase %s in stop condition; must be one of rapi, trig, shot or inac", s))
+}
+
+/*
+Reset the stop condition for a new replicate
+*/
+func (sc *StopCondition) reset() {
+	sc.metAt = -1
+	for _, clause := range sc.clauses {
+		for _, term := range clause {
+			term.history = nil
+			term.consecutive = 0
+		}
+	}
+}
+
+/*
+Update the stop condition with the population of the given generation;
+returns true if the simulation should stop, i.e. 'extra' generations after the condition was met
+*/
+func (sc *StopCondition) isStopped(p *fly.Population, replicate int64, generation int64) bool {
+	if len(sc.clauses) == 0 {
+		return false
+	}
+	if sc.metAt < 0 && sc.evaluate(p) {
+		sc.metAt = generation
+		util.InvadeLogger.Printf("Replicate %d: stop condition '%s' met at generation %d", replicate, sc.expression, generation)
+	}
+	return sc.metAt >= 0 && generation >= sc.metAt+sc.extra
+}
+
+func (sc *StopCondition) evaluate(p *fly.Population) bool {
+	// all terms need to be updated every generation (history of the sliding windows and consecutive generations), no short-circuit
+	met := false
+	for _, clause := range sc.clauses {
+		clausemet := true
+		for _, term := range clause {
+			if !term.update(term.get(p)) {
+				clausemet = false
+			}
+		}
+		if clausemet {
+			met = true
+		}
+	}
+	return met
+}
+
+/*
+Update the term with the value of the current generation; returns true if the term held for the required number of consecutive generations
+*/
+func (t *stopTerm) update(value float64) bool {
+	var holds bool
+	if t.stationary {
+		t.history = append(t.history, value)
+		if int64(len(t.history)) > t.window {
+			t.history = t.history[1:]
+		}
+		holds = int64(len(t.history)) == t.window && isStationary(t.history, t.tolerance)
+	} else {
+		holds = compare(value, t.operator, t.value)
+	}
+	if holds {
+		t.consecutive++
+	} else {
+		t.consecutive = 0
+	}
+	return t.consecutive >= t.duration
+}
+
+func compare(value float64, operator string, threshold float64) bool {
+	switch operator {
+	case "==":
+		return value == threshold
+	case "!=":
+		return value != threshold
+	case ">=":
+		return value >= threshold
+	case "<=":
+		return value <= threshold
+	case ">":
+		return value > threshold
+	case "<":
+		return value < threshold
+	}
+	panic(fmt.Sprintf("unknown operator %s", operator))
+}
+
+/*
+Test whether a time series is stationary; the change over the window predicted by a least-squares regression
+must be smaller than 'tolerance' times the mean of the window
+*/
+func isStationary(values []float64, tolerance float64) bool {
+	n := float64(len(values))
+	meanx := (n - 1.0) / 2.0
+	meany := 0.0
+	for _, v := range values {
+		meany += v
+	}
+	meany /= n
+	var sxy, sxx float64
+	for i, v := range values {
+		dx := float64(i) - meanx
+		sxy += dx * (v - meany)
+		sxx += dx * dx
+	}
+	slope := sxy / sxx
+	change := math.Abs(slope * (n - 1.0))
+	return change <= tolerance*math.Abs(meany)
+}
@@ -0,0 +1,77 @@
+package sim
+
+import (
+	"invade/fly"
+	"testing"
+)
+
+func TestParseStopCondition(t *testing.T) {
+	var tests = []struct {
+		expression string
+		clauses    int
+		terms      int
+	}{
+		{expression: "", clauses: 0, terms: 0},
+		{expression: "fwpirna>0.99", clauses: 1, terms: 1},
+		{expression: "phase==inac for 100", clauses: 1, terms: 1},
+		{expression: "stationary(avtes,200) && fwpirna>=0.9 || gen>1000", clauses: 2, terms: 3},
+	}
+	for _, test := range tests {
+		clauses := parseStopCondition(test.expression)
+		terms := 0
+		for _, c := range clauses {
+			terms += len(c)
+		}
+		if len(clauses) != test.clauses || terms != test.terms {
+			t.Errorf("Incorrect parsing of stop condition %s; got %d clauses and %d terms", test.expression, len(clauses), terms)
+		}
+	}
+	term := parseStopTerm("phase==inac for 100")
+	if term.value != float64(fly.INACTIVE) || term.duration != 100 || term.operator != "==" {
+		t.Errorf("Incorrect parsing of phase condition; got %v", term)
+	}
+	term = parseStopTerm("stationary(avcli, 50, 0.1)")
+	if !term.stationary || term.window != 50 || term.tolerance != 0.1 || term.duration != 1 {
+		t.Errorf("Incorrect parsing of stationarity condition; got %v", term)
+	}
+}
+
+func TestStopTermUpdate(t *testing.T) {
+	var tests = []struct {
+		term   string
+		values []float64
+		want   []bool
+	}{
+		{term: "fwpirna>0.5", values: []float64{0.1, 0.6, 0.4}, want: []bool{false, true, false}},
+		{term: "fwpirna>0.5 for 2", values: []float64{0.6, 0.4, 0.6, 0.7, 0.8}, want: []bool{false, false, false, true, true}},
+		{term: "avtes!=0", values: []float64{0, 1}, want: []bool{false, true}},
+		{term: "stationary(avtes,3)", values: []float64{1, 2, 3, 10, 10, 10}, want: []bool{false, false, false, false, false, true}},
+		{term: "stationary(avtes,4,0.1) for 2", values: []float64{10, 10.2, 9.9, 10, 10.1, 1}, want: []bool{false, false, false, false, true, false}},
+	}
+	for _, test := range tests {
+		term := parseStopTerm(test.term)
+		for i, v := range test.values {
+			if got := term.update(v); got != test.want[i] {
+				t.Errorf("Incorrect evaluation of %s at step %d; want %t, got %t", test.term, i, test.want[i], got)
+			}
+		}
+	}
+}
+
+func TestIsStationary(t *testing.T) {
+	var tests = []struct {
+		values    []float64
+		tolerance float64
+		want      bool
+	}{
+		{values: []float64{5, 5, 5}, tolerance: 0.0, want: true},
+		{values: []float64{0, 0, 0}, tolerance: 0.05, want: true},
+		{values: []float64{1, 2, 3, 4}, tolerance: 0.05, want: false},
+		{values: []float64{100, 101, 99, 100}, tolerance: 0.05, want: true},
+	}
+	for _, test := range tests {
+		if got := isStationary(test.values, test.tolerance); got != test.want {
+			t.Errorf("Incorrect stationarity of %v; want %t, got %t", test.values, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+/*
+Parameter sweeps over grids of model parameters; each combination of parameters is simulated by a separate invade process,
+the processes run in parallel and the output lines are tagged with the parameter values
+*/
+package sweep
+
+import (
+	"fmt"
+	"invade/io/writer"
+	"invade/runner"
+	"invade/util"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+A parameter of the sweep; the name of a command line parameter of the simulations (e.g. u, x, cluster, N) and its values
+*/
+type Parameter struct {
+	Name   string
+	Values []string
+}
+
+/*
+A single combination of parameter values; simulated with its own seed, derived from the seed of the sweep
+*/
+type Combination struct {
+	Index  int64
+	Values []string
+	Seed   int64
+}
+
+type Sweep struct {
+	parameters    []Parameter
+	args          []string // command line parameters passed to every simulation
+	threads       int64
+	summariesOnly bool // solely the per-replicate summaries are written
+	seed          int64
+	out           *writer.Output
+}
+
+type runResult struct {
+	combination Combination
+	lines       []string // the header and the output lines of the simulation
+	err         error
+}
+
+/*
+The command line parameters of the sweep; all other parameters are passed to the simulations
+*/
+var sweepFlags = map[string]bool{"grid": false, "threads": false, "summaries-only": true, "seed": false, "out": false} // name -> is boolean
+
+var rangeValue = regexp.MustCompile(`^(.*?)(-?[0-9.]+(?:[eE][-+]?[0-9]+)?)\s*\.\.\s*(-?[0-9.]+(?:[eE][-+]?[0-9]+)?)(?:\s+step\s+([0-9.]+(?:[eE][-+]?[0-9]+)?))?$`)
+
+func PrintUsage() {
+	fmt.Fprintln(os.Stderr, `Usage: invade sweep --grid "u=0.05,0.1,0.2; cluster=kb:100..1000 step 100" [sweep options] [simulation parameters]
+
+Simulates each combination of the parameter values (cartesian product) as a separate run, in parallel;
+each output line is tagged with the parameter values and the seed of the run.
+
+Sweep options:
+  --grid string       the parameters of the sweep separated by ';'; values are either lists (e.g. u=0.05,0.1,0.2),
+                      lists separated by '|' if the values contain commas (e.g. rr=4,4|2,2) or ranges (e.g. N=100..1000 step 100;
+                      a prefix is kept, e.g. cluster=kb:100..1000 step 100 yields kb:100, kb:200, ..)
+  --threads int       number of simulations run in parallel (default: number of cores)
+  --summaries-only    solely write the per-replicate summaries (see --file-summary) instead of the main table
+  --seed int          seed of the sweep; the seeds of the runs are derived from it
+  --out string        output file of the sweep; default is stdout
+
+All other parameters (e.g. --N 1000 --gen 5000 --genome MB:2 --rr 4 --basepop 100 --rep 10) are passed to each simulation;
+the output files (--file-*) are specific to each run, e.g. --file-mhp mhp.txt yields mhp.run1.txt, mhp.run2.txt, ..
+(the runs are numbered in the order of the combinations, the values of the last parameter of the grid vary fastest)`)
+}
+
+/*
+Run a parameter sweep; the arguments are the command line parameters following the 'sweep' subcommand;
+missing or invalid parameters of the sweep yield an error (after the usage is printed)
+*/
+func Run(args []string) error {
+	opts, passthrough := runner.SplitArgs(args, sweepFlags)
+	if _, ok := opts["help"]; ok {
+		PrintUsage()
+		return nil
+	}
+	if opts["grid"] == "" {
+		PrintUsage()
+		return fmt.Errorf("Provide a suitable grid of parameters --grid")
+	}
+	threads := int64(runtime.NumCPU())
+	if t, ok := opts["threads"]; ok {
+		var err error
+		if threads, err = strconv.ParseInt(t, 10, 64); err != nil || threads < 1 {
+			PrintUsage()
+			return fmt.Errorf("Provide a suitable number of threads --threads; must be larger or equal to 1")
+		}
+	}
+	seed := time.Now().UnixNano()
+	if s, ok := opts["seed"]; ok {
+		var err error
+		if seed, err = strconv.ParseInt(s, 10, 64); err != nil {
+			PrintUsage()
+			return fmt.Errorf("Provide a suitable seed --seed")
+		}
+	}
+	for _, a := range passthrough {
+		if a == "--silent" || a == "-silent" {
+			util.InvadeLogger.SetOutput(ioutil.Discard)
+		}
+	}
+	out := writer.StdoutOutput()
+	if opts["out"] != "" {
+		out = writer.CreateOutput(opts["out"])
+	}
+	defer out.Close()
+
+	sw := &Sweep{parameters: ParseGrid(opts["grid"]), args: passthrough, threads: threads, summariesOnly: opts["summaries-only"] == "true", seed: seed, out: out}
+	sw.run()
+	return nil
+}
+
+/*
+Parse the grid of parameters, e.g. "u=0.05,0.1,0.2; cluster=kb:100..1000 step 100"
+*/
+func ParseGrid(grid string) []Parameter {
+	params := []Parameter{}
+	unique := make(map[string]bool)
+	for _, entry := range strings.Split(grid, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tmp := strings.SplitN(entry, "=", 2)
+		name := strings.TrimLeft(strings.TrimSpace(tmp[0]), "-")
+		if len(tmp) != 2 || name == "" {
+			panic(fmt.Sprintf("Invalid parameter of the sweep %s; must be name=values", entry))
+		}
+		if unique[name] {
+			panic(fmt.Sprintf("Invalid grid of the sweep; parameter %s is provided multiple times", name))
+		}
+		if _, ok := sweepFlags[name]; ok {
+			panic(fmt.Sprintf("Invalid grid of the sweep; %s is a parameter of the sweep", name))
+		}
+		unique[name] = true
+		params = append(params, Parameter{Name: name, Values: parseValues(strings.TrimSpace(tmp[1]))})
+	}
+	if len(params) == 0 {
+		panic(fmt.Sprintf("Invalid grid of the sweep %s; no parameters", grid))
+	}
+	return params
+}
+
+/*
+Parse the values of a parameter; a range (e.g. kb:100..1000 step 100), a list separated by '|' or a list separated by commas
+*/
+func parseValues(s string) []string {
+	if m := rangeValue.FindStringSubmatch(s); m != nil {
+		return parseRange(s, m[1], m[2], m[3], m[4])
+	}
+	sep := ","
+	if strings.Contains(s, "|") {
+		sep = "|"
+	}
+	values := []string{}
+	for _, v := range strings.Split(s, sep) {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			panic(fmt.Sprintf("Invalid values of sweep parameter %s; empty value", s))
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+func parseRange(s string, prefix string, start string, end string, step string) []string {
+	from, err1 := strconv.ParseFloat(start, 64)
+	to, err2 := strconv.ParseFloat(end, 64)
+	by := 1.0
+	var err3 error
+	if step != "" {
+		by, err3 = strconv.ParseFloat(step, 64)
+	}
+	if err1 != nil || err2 != nil || err3 != nil || by <= 0.0 || to < from {
+		panic(fmt.Sprintf("Invalid range of sweep parameter %s; e.g. 100..1000 step 100", s))
+	}
+	values := []string{}
+	count := int64(math.Floor((to-from)/by+1e-9)) + 1
+	for i := int64(0); i < count; i++ {
+		v := from + float64(i)*by
+		values = append(values, prefix+strconv.FormatFloat(roundRange(v), 'f', -1, 64))
+	}
+	return values
+}
+
+/*
+Remove floating point artefacts of the ranges, e.g. 0.30000000000000004
+*/
+func roundRange(v float64) float64 {
+	r, _ := strconv.ParseFloat(strconv.FormatFloat(v, 'g', 12, 64), 64)
+	return r
+}
+
+/*
+The cartesian product of the parameter values; the last parameter varies fastest
+*/
+func expandGrid(params []Parameter) [][]string {
+	combinations := [][]string{{}}
+	for _, p := range params {
+		next := [][]string{}
+		for _, c := range combinations {
+			for _, v := range p.Values {
+				nc := append(append([]string{}, c...), v)
+				next = append(next, nc)
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+func (sw *Sweep) getCombinations() []Combination {
+	toret := []Combination{}
+	for i, values := range expandGrid(sw.parameters) {
+		toret = append(toret, Combination{Index: int64(i), Values: values, Seed: util.DeriveSeed(sw.seed, int64(i))})
+	}
+	return toret
+}
+
+/*
+Run all combinations in parallel; the results are written in the order of the combinations
+*/
+func (sw *Sweep) run() {
+	exe, err := os.Executable()
+	if err != nil {
+		panic(err)
+	}
+	tmpdir, err := ioutil.TempDir("", "invade-sweep")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	combinations := sw.getCombinations()
+	util.InvadeLogger.Printf("Sweep: %d combinations of %d parameters, %d in parallel, seed %d", len(combinations), len(sw.parameters), sw.threads, sw.seed)
+	jobs := make(chan Combination)
+	results := make(chan runResult)
+	var wg sync.WaitGroup
+	for t := int64(0); t < sw.threads; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range jobs {
+				lines, err := sw.runCombination(exe, tmpdir, c)
+				results <- runResult{combination: c, lines: lines, err: err}
+			}
+		}()
+	}
+	go func() {
+		for _, c := range combinations {
+			jobs <- c
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := make(map[int64]runResult)
+	var next int64
+	headerWritten := false
+	failed := 0
+	for r := range results {
+		pending[r.combination.Index] = r
+		for {
+			res, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if res.err != nil {
+				failed++
+				util.InvadeLogger.Printf("Sweep: run %d (%s) failed: %v", res.combination.Index+1, sw.formatCombination(res.combination), res.err)
+				continue
+			}
+			util.InvadeLogger.Printf("Sweep: run %d of %d done (%s)", res.combination.Index+1, len(combinations), sw.formatCombination(res.combination))
+			if len(res.lines) == 0 {
+				continue
+			}
+			if !headerWritten {
+				header := []string{}
+				for _, p := range sw.parameters {
+					header = append(header, p.Name)
+				}
+				header = append(header, "seed", res.lines[0])
+				sw.out.WriteString(strings.Join(header, "\t") + "\n")
+				headerWritten = true
+			}
+			tag := strings.Join(append(append([]string{}, res.combination.Values...), fmt.Sprintf("%d", res.combination.Seed)), "\t")
+			for _, line := range res.lines[1:] {
+				sw.out.WriteString(tag + "\t" + line + "\n")
+			}
+		}
+	}
+	if failed > 0 {
+		panic(fmt.Sprintf("Sweep: %d of %d runs failed", failed, len(combinations)))
+	}
+}
+
+func (sw *Sweep) formatCombination(c Combination) string {
+	tmp := []string{}
+	for i, p := range sw.parameters {
+		tmp = append(tmp, p.Name+"="+c.Values[i])
+	}
+	return strings.Join(tmp, " ")
+}
+
+/*
+The command line parameters of a single run; the parameters of the sweep are appended, thus they overrule the passed parameters
+*/
+func (sw *Sweep) getRunArgs(c Combination, summaryFile string) []string {
+	args := runner.TagOutputFiles(sw.args, fmt.Sprintf("run%d", c.Index+1))
+	for i, p := range sw.parameters {
+		args = append(args, "--"+p.Name, c.Values[i])
+	}
+	args = append(args, "--seed", fmt.Sprintf("%d", c.Seed), "--silent", "--format", "tsv")
+	if sw.summariesOnly {
+		args = append(args, "--file-summary", summaryFile, "--file-main", os.DevNull)
+	}
+	return args
+}
+
+/*
+Simulate a single combination; returns the lines of the main table or of the summary (including the header)
+*/
+func (sw *Sweep) runCombination(exe string, tmpdir string, c Combination) ([]string, error) {
+	summaryFile := filepath.Join(tmpdir, fmt.Sprintf("run%d.summary.txt", c.Index+1))
+	content, err := runner.RunSimulation(exe, sw.getRunArgs(c, summaryFile))
+	if err != nil {
+		return nil, err
+	}
+	if sw.summariesOnly {
+		b, err := ioutil.ReadFile(summaryFile)
+		if err != nil {
+			return nil, err
+		}
+		os.Remove(summaryFile)
+		content = string(b)
+	}
+	return runner.SplitLines(content), nil
+}
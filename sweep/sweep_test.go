@@ -0,0 +1,60 @@
+package sweep
+
+import (
+	"invade/runner"
+	"strings"
+	"testing"
+)
+
+func TestParseGrid(t *testing.T) {
+	var tests = []struct {
+		grid string
+		want map[string]string
+	}{
+		{grid: "u=0.05,0.1,0.2", want: map[string]string{"u": "0.05 0.1 0.2"}},
+		{grid: "u=0.05,0.1; cluster=kb:100..300 step 100", want: map[string]string{"u": "0.05 0.1", "cluster": "kb:100 kb:200 kb:300"}},
+		{grid: "x=0..0.3 step 0.1", want: map[string]string{"x": "0 0.1 0.2 0.3"}},
+		{grid: "N=100..103", want: map[string]string{"N": "100 101 102 103"}},
+		{grid: "--rr=4,4|2,2", want: map[string]string{"rr": "4,4 2,2"}},
+	}
+	for _, test := range tests {
+		params := ParseGrid(test.grid)
+		if len(params) != len(test.want) {
+			t.Errorf("Incorrect number of parameters of %s; got %v", test.grid, params)
+		}
+		for _, p := range params {
+			if got := strings.Join(p.Values, " "); got != test.want[p.Name] {
+				t.Errorf("Incorrect values of %s in %s; want %s, got %s", p.Name, test.grid, test.want[p.Name], got)
+			}
+		}
+	}
+}
+
+func TestExpandGrid(t *testing.T) {
+	params := []Parameter{{Name: "u", Values: []string{"0.1", "0.2"}}, {Name: "x", Values: []string{"0", "1", "2"}}}
+	got := expandGrid(params)
+	want := []string{"0.1 0", "0.1 1", "0.1 2", "0.2 0", "0.2 1", "0.2 2"}
+	if len(got) != len(want) {
+		t.Fatalf("Incorrect number of combinations; want %d, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if strings.Join(got[i], " ") != want[i] {
+			t.Errorf("Incorrect combination %d; want %s, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	opts, passthrough := runner.SplitArgs([]string{"--grid", "u=0.1,0.2", "--N", "100", "--summaries-only", "--threads=4", "--silent", "--seed", "7"}, sweepFlags)
+	if opts["grid"] != "u=0.1,0.2" || opts["summaries-only"] != "true" || opts["threads"] != "4" || opts["seed"] != "7" {
+		t.Errorf("Incorrect sweep parameters; got %v", opts)
+	}
+	if strings.Join(passthrough, " ") != "--N 100 --silent" {
+		t.Errorf("Incorrect simulation parameters; got %v", passthrough)
+	}
+	sw := &Sweep{parameters: []Parameter{{Name: "u", Values: []string{"0.1"}}}, args: passthrough, summariesOnly: true}
+	args := strings.Join(sw.getRunArgs(Combination{Values: []string{"0.1"}, Seed: 5}, "run1.txt"), " ")
+	if !strings.HasPrefix(args, "--N 100 --silent --u 0.1 --seed 5") || !strings.Contains(args, "--file-summary run1.txt") {
+		t.Errorf("Incorrect arguments of a run; got %s", args)
+	}
+}
@@ -0,0 +1,165 @@
+package treeseq
+
+import (
+	"sort"
+)
+
+/*
+An ancestral segment; the interval [left, right) of an input node is ancestral to the output node
+*/
+type segment struct {
+	left  int64
+	right int64
+	node  int64
+}
+
+/*
+Simplify the tables with respect to the samples (see Kelleher et al. 2018, Efficient pedigree recording for fast population genetics simulation);
+Only nodes and edges that are ancestral to the samples are retained; nodes are only retained if they are coalescent nodes.
+The samples are the first nodes in the simplified tables, followed by the ancestral nodes (youngest first).
+Mutations are mapped to the retained nodes; mutations that are not ancestral to the samples are removed.
+The samples must not be parents of other nodes, e.g. the haplotypes of the current generation
+*/
+func simplify(t *TableCollection, samples []int64) *TableCollection {
+	out := &TableCollection{SequenceLength: t.SequenceLength}
+	ancestry := make(map[int64][]segment)
+	for _, s := range samples {
+		id := int64(len(out.Nodes))
+		out.Nodes = append(out.Nodes, t.Nodes[s])
+		ancestry[s] = []segment{{left: 0, right: t.SequenceLength, node: id}}
+	}
+
+	// process the parents from the youngest to the oldest
+	byParent := make(map[int64][]Edge)
+	for _, e := range t.Edges {
+		byParent[e.Parent] = append(byParent[e.Parent], e)
+	}
+	parents := make([]int64, 0, len(byParent))
+	for p := range byParent {
+		parents = append(parents, p)
+	}
+	sort.Slice(parents, func(i, j int) bool {
+		bi, bj := t.Nodes[parents[i]].Birth, t.Nodes[parents[j]].Birth
+		if bi != bj {
+			return bi > bj
+		}
+		return parents[i] < parents[j]
+	})
+
+	for _, u := range parents {
+		var q []segment
+		for _, e := range byParent[u] {
+			for _, x := range ancestry[e.Child] {
+				if x.right > e.Left && e.Right > x.left {
+					q = append(q, segment{left: maxInt64(x.left, e.Left), right: minInt64(x.right, e.Right), node: x.node})
+				}
+			}
+		}
+		if len(q) > 0 {
+			ancestry[u] = mergeAncestors(out, q, t.Nodes[u])
+		}
+	}
+
+	// map the mutations to the retained nodes
+	for _, m := range t.Mutations {
+		for _, x := range ancestry[m.Node] {
+			if m.Site >= x.left && m.Site < x.right {
+				out.Mutations = append(out.Mutations, Mutation{Node: x.node, Site: m.Site})
+				break
+			}
+		}
+	}
+	sort.SliceStable(out.Mutations, func(i, j int) bool {
+		mi, mj := out.Mutations[i], out.Mutations[j]
+		if mi.Site != mj.Site {
+			return mi.Site < mj.Site
+		}
+		return out.Nodes[mi.Node].Birth < out.Nodes[mj.Node].Birth // older mutations first
+	})
+	return out
+}
+
+/*
+Merge the ancestral segments of the children of a parent;
+intervals covered by a single segment are passed through the parent, intervals covered by multiple segments coalesce in the parent, which thus becomes an output node.
+Returns the ancestral segments of the parent
+*/
+func mergeAncestors(out *TableCollection, q []segment, parent Node) []segment {
+	boundaries := make(map[int64]bool)
+	for _, x := range q {
+		boundaries[x.left] = true
+		boundaries[x.right] = true
+	}
+	bounds := make([]int64, 0, len(boundaries))
+	for b := range boundaries {
+		bounds = append(bounds, b)
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	var v int64 = -1
+	var anc []segment
+	var edges []Edge
+	for i := 0; i+1 < len(bounds); i++ {
+		left, right := bounds[i], bounds[i+1]
+		cover := make([]int64, 0)
+		for _, x := range q {
+			if x.left <= left && x.right >= right {
+				cover = append(cover, x.node)
+			}
+		}
+		if len(cover) == 0 {
+			continue
+		} else if len(cover) == 1 {
+			anc = appendSegment(anc, segment{left: left, right: right, node: cover[0]})
+			continue
+		}
+		if v == -1 {
+			out.Nodes = append(out.Nodes, parent)
+			v = int64(len(out.Nodes) - 1)
+		}
+		for _, c := range cover {
+			edges = append(edges, Edge{Left: left, Right: right, Parent: v, Child: c})
+		}
+		anc = appendSegment(anc, segment{left: left, right: right, node: v})
+	}
+
+	// squash adjacent edges of the same child
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Child != edges[j].Child {
+			return edges[i].Child < edges[j].Child
+		}
+		return edges[i].Left < edges[j].Left
+	})
+	for _, e := range edges {
+		last := len(out.Edges) - 1
+		if last >= 0 && out.Edges[last].Parent == e.Parent && out.Edges[last].Child == e.Child && out.Edges[last].Right == e.Left {
+			out.Edges[last].Right = e.Right
+		} else {
+			out.Edges = append(out.Edges, e)
+		}
+	}
+	return anc
+}
+
+func appendSegment(segs []segment, s segment) []segment {
+	last := len(segs) - 1
+	if last >= 0 && segs[last].node == s.node && segs[last].right == s.left {
+		segs[last].right = s.right
+		return segs
+	}
+	return append(segs, s)
+}
+
+func minInt64(a int64, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt64(a int64, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
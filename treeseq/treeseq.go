@@ -0,0 +1,129 @@
+/*
+Recording of the genealogy of the simulated haplotypes as tree sequence (node, edge and mutation tables);
+compatible with tskit
+*/
+package treeseq
+
+/*
+A node is a haplotype; each fly has two nodes.
+Nodes are recorded in forward time, i.e. the generation in which the haplotype was born
+*/
+type Node struct {
+	Birth int64
+}
+
+/*
+An edge; the child node inherited the interval [Left, Right) from the parent node
+*/
+type Edge struct {
+	Left   int64
+	Right  int64
+	Parent int64
+	Child  int64
+}
+
+/*
+A mutation, i.e. a novel TE insertion at a given site (genomic position) in a node
+*/
+type Mutation struct {
+	Node int64
+	Site int64
+}
+
+type TableCollection struct {
+	Nodes          []Node
+	Edges          []Edge
+	Mutations      []Mutation
+	SequenceLength int64
+}
+
+type Recorder struct {
+	active   bool
+	interval int64 // simplify the tables every 'interval' generations
+	tables   *TableCollection
+}
+
+var recorder = Recorder{active: false}
+
+func SetupRecorder(active bool, simplifyInterval int64) {
+	if active && simplifyInterval < 1 {
+		panic("invalid simplification interval for the tree sequence; must be larger or equal to 1")
+	}
+	recorder = Recorder{active: active, interval: simplifyInterval}
+}
+
+func IsActive() bool {
+	return recorder.active
+}
+
+/*
+Start recording the genealogy of a novel replicate
+*/
+func Reset(sequenceLength int64) {
+	recorder.tables = &TableCollection{SequenceLength: sequenceLength}
+}
+
+/*
+Is it time to simplify the tables?
+*/
+func NeedsSimplification(generation int64) bool {
+	return recorder.active && generation%recorder.interval == 0
+}
+
+func GetTables() *TableCollection {
+	return recorder.tables
+}
+
+/*
+Add a node (haplotype) born in the given generation; returns the id of the node
+*/
+func AddNode(birth int64) int64 {
+	t := recorder.tables
+	t.Nodes = append(t.Nodes, Node{Birth: birth})
+	return int64(len(t.Nodes) - 1)
+}
+
+/*
+Add the edges for a recombined gamete; the gamete starts with the first parental haplotype and switches
+the haplotype at each recombination event (RECOMBINATION FIRST; i.e. the event position belongs to the next segment)
+*/
+func AddGamete(parent1 int64, parent2 int64, child int64, recombinationEvents []int64) {
+	t := recorder.tables
+	parents := [2]int64{parent1, parent2}
+	active := 0
+	var left int64 = 0
+	for _, r := range recombinationEvents {
+		if r > left {
+			t.Edges = append(t.Edges, Edge{Left: left, Right: r, Parent: parents[active], Child: child})
+		}
+		left = r
+		active = 1 - active
+	}
+	if left < t.SequenceLength {
+		t.Edges = append(t.Edges, Edge{Left: left, Right: t.SequenceLength, Parent: parents[active], Child: child})
+	}
+}
+
+/*
+Add mutations (novel insertions) to a node
+*/
+func AddMutations(node int64, sites []int64) {
+	t := recorder.tables
+	for _, s := range sites {
+		t.Mutations = append(t.Mutations, Mutation{Node: node, Site: s})
+	}
+}
+
+/*
+Simplify the tables with respect to the given samples;
+returns the novel ids of the samples, in the order of the samples
+*/
+func Simplify(samples []int64) []int64 {
+	simplified := simplify(recorder.tables, samples)
+	recorder.tables = simplified
+	toret := make([]int64, len(samples))
+	for i := range samples {
+		toret[i] = int64(i) // samples are the first nodes in the simplified tables
+	}
+	return toret
+}
@@ -0,0 +1,76 @@
+package treeseq
+
+import (
+	"testing"
+)
+
+func TestAddGamete(test *testing.T) {
+	var tests = []struct {
+		events []int64
+		want   []Edge
+	}{
+		{events: []int64{}, want: []Edge{{0, 100, 1, 2}}},
+		{events: []int64{0}, want: []Edge{{0, 100, 0, 2}}},
+		{events: []int64{50}, want: []Edge{{0, 50, 1, 2}, {50, 100, 0, 2}}},
+		{events: []int64{0, 50}, want: []Edge{{0, 50, 0, 2}, {50, 100, 1, 2}}},
+		{events: []int64{20, 50, 70}, want: []Edge{{0, 20, 1, 2}, {20, 50, 0, 2}, {50, 70, 1, 2}, {70, 100, 0, 2}}},
+	}
+	for _, t := range tests {
+		Reset(100)
+		AddGamete(1, 0, 2, t.events)
+		got := GetTables().Edges
+		if len(got) != len(t.want) {
+			test.Errorf("Incorrect edges for events %v; got %v want %v", t.events, got, t.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != t.want[i] {
+				test.Errorf("Incorrect edges for events %v; got %v want %v", t.events, got, t.want)
+			}
+		}
+	}
+}
+
+func TestSimplify(test *testing.T) {
+	// generation 0: nodes 0,1; generation 1: nodes 2,3; generation 2: nodes 4,5 (samples)
+	Reset(100)
+	for _, b := range []int64{0, 0, 1, 1, 2, 2} {
+		AddNode(b)
+	}
+	AddGamete(0, 1, 2, []int64{50}) // [0,50) from 0; [50,100) from 1
+	AddGamete(0, 1, 3, []int64{})   // entirely from 0
+	AddGamete(2, 3, 4, []int64{})   // entirely from 2
+	AddGamete(2, 3, 5, []int64{0})  // entirely from 3
+	AddMutations(0, []int64{10})    // ancestral to both samples
+	AddMutations(1, []int64{60})    // ancestral to sample 4
+	AddMutations(3, []int64{70})    // ancestral to sample 5
+	AddMutations(1, []int64{30})    // not ancestral to any sample
+
+	ids := Simplify([]int64{4, 5})
+	if ids[0] != 0 || ids[1] != 1 {
+		test.Errorf("Incorrect ids of the samples %v", ids)
+	}
+	got := GetTables()
+	// node 0 is the sole coalescent node on [0,50); [50,100) does not coalesce
+	if len(got.Nodes) != 3 || got.Nodes[2].Birth != 0 {
+		test.Errorf("Incorrect nodes %v", got.Nodes)
+	}
+	wantedges := []Edge{{0, 50, 2, 0}, {0, 50, 2, 1}}
+	if len(got.Edges) != len(wantedges) {
+		test.Fatalf("Incorrect edges %v", got.Edges)
+	}
+	for i := range wantedges {
+		if got.Edges[i] != wantedges[i] {
+			test.Errorf("Incorrect edges %v", got.Edges)
+		}
+	}
+	wantmut := []Mutation{{Node: 2, Site: 10}, {Node: 0, Site: 60}, {Node: 1, Site: 70}}
+	if len(got.Mutations) != len(wantmut) {
+		test.Fatalf("Incorrect mutations %v", got.Mutations)
+	}
+	for i := range wantmut {
+		if got.Mutations[i] != wantmut[i] {
+			test.Errorf("Incorrect mutations %v", got.Mutations)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"fmt"
 	"math"
 	"math/rand"
 	"time"
@@ -29,6 +30,35 @@ func Variance(data []int64) float64 {
 	return variance
 }
 
+/*
+Quantile of sorted data; linear interpolation between the closest ranks
+*/
+func Quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0.0
+	}
+	if q < 0.0 || q > 1.0 {
+		panic("invalid quantile; must be between 0.0 and 1.0")
+	}
+	rank := q * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	frac := rank - float64(lo)
+	return sorted[lo] + frac*(sorted[hi]-sorted[lo])
+}
+
+/*
+Derive an independent seed from a seed (splitmix64), e.g. of the runs of a sweep or of the separate random number generators
+of the outputs (pool-seq, VCF); the derived seeds of different indices yield unrelated random numbers
+*/
+func DeriveSeed(seed int64, index int64) int64 {
+	z := uint64(seed) + uint64(index+1)*0x9E3779B97F4A7C15
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	z = z ^ (z >> 31)
+	return int64(z >> 1) // positive seeds; -1 would request a random seed
+}
+
 func SetSeed(seed int64) int64 {
 	if seed != -1 {
 		InvadeLogger.Printf("Will use seed provided by user: %d", seed)
@@ -42,6 +72,19 @@ func SetSeed(seed int64) int64 {
 	}
 }
 
+/*
+Bernoulli trial; returns true with probability p.
+No random number is drawn for p <= 0.0 or p >= 1.0, which keeps simulations with deterministic settings reproducible with a given seed
+*/
+func Bernoulli(p float64) bool {
+	if p <= 0.0 {
+		return false
+	} else if p >= 1.0 {
+		return true
+	}
+	return rand.Float64() < p
+}
+
 /*
 Deprecated
 has numerical problem when lambda >>700
@@ -67,6 +110,13 @@ func PoissonSimple(lambda float64) int64 {
 Poisson distributed random numbers; works even when lambda >>700
 */
 func Poisson(lambda float64) int64 {
+	return PoissonFrom(rand.Float64, lambda)
+}
+
+/*
+Poisson distributed random numbers from the given source of uniform random numbers (e.g. of a separate random number generator)
+*/
+func PoissonFrom(uniform func() float64, lambda float64) int64 {
 	// perfect implementation; solves numerical problem when lambda >700?
 	lleft := lambda
 	step := 500.0
@@ -74,7 +124,7 @@ func Poisson(lambda float64) int64 {
 	var k int64 = 0
 	for ok := true; ok; ok = p > 1.0 {
 		k++
-		r := rand.Float64()
+		r := uniform()
 		p = p * r
 		for p < 1.0 && lleft > 0.0 {
 			if lleft > step {
@@ -92,3 +142,40 @@ func Poisson(lambda float64) int64 {
 	}
 	return ret
 }
+
+/*
+Gamma distributed random numbers with the given shape and a scale of 1 (Marsaglia and Tsang 2000);
+the uniform and standard normal random numbers are taken from the given sources (e.g. of a separate random number generator)
+*/
+func GammaFrom(uniform func() float64, normal func() float64, shape float64) float64 {
+	if shape <= 0.0 {
+		panic(fmt.Sprintf("invalid shape of gamma distribution %f; must be larger than zero", shape))
+	}
+	if shape < 1.0 {
+		// boost the shape; see Marsaglia and Tsang
+		return GammaFrom(uniform, normal, shape+1.0) * math.Pow(uniform(), 1.0/shape)
+	}
+	d := shape - 1.0/3.0
+	c := 1.0 / math.Sqrt(9.0*d)
+	for {
+		x := normal()
+		v := 1.0 + c*x
+		if v <= 0.0 {
+			continue
+		}
+		v = v * v * v
+		u := uniform()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
+
+/*
+Beta distributed random numbers; obtained from two gamma distributed random numbers
+*/
+func Beta(alpha float64, beta float64) float64 {
+	x := GammaFrom(rand.Float64, rand.NormFloat64, alpha)
+	y := GammaFrom(rand.Float64, rand.NormFloat64, beta)
+	return x / (x + y)
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"math/rand"
 	"testing"
 )
 
@@ -77,3 +78,69 @@ func TestPoissonHigh(t *testing.T) {
 		t.Error("incorrect Poisson distribution")
 	}
 }
+
+func TestStochasticBernoulli(t *testing.T) {
+	SetSeed(3)
+	if Bernoulli(0.0) || !Bernoulli(1.0) {
+		t.Error("incorrect Bernoulli trial for p=0 or p=1")
+	}
+	count := 0
+	for i := 0; i < 10000; i++ {
+		if Bernoulli(0.3) {
+			count++
+		}
+	}
+	if count < 2900 || count > 3100 {
+		t.Errorf("incorrect Bernoulli trials; should be around 3000, got %d", count)
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	var tests = []struct {
+		data []float64
+		q    float64
+		want float64
+	}{
+		{data: []float64{}, q: 0.5, want: 0.0},
+		{data: []float64{2}, q: 0.5, want: 2.0},
+		{data: []float64{1, 2, 3}, q: 0.5, want: 2.0},
+		{data: []float64{1, 2, 3, 4}, q: 0.5, want: 2.5},
+		{data: []float64{1, 2, 3, 4}, q: 0.0, want: 1.0},
+		{data: []float64{1, 2, 3, 4}, q: 1.0, want: 4.0},
+		{data: []float64{0, 10}, q: 0.25, want: 2.5},
+	}
+	for _, test := range tests {
+		if got := Quantile(test.data, test.q); got != test.want {
+			t.Errorf("Quantile(%v,%f)!=%f; got %f", test.data, test.q, test.want, got)
+		}
+	}
+}
+
+func TestDeriveSeed(t *testing.T) {
+	seen := make(map[int64]bool)
+	for i := int64(0); i < 1000; i++ {
+		s := DeriveSeed(42, i)
+		if s < 0 || seen[s] {
+			t.Errorf("Invalid derived seed %d for run %d", s, i)
+		}
+		seen[s] = true
+		if DeriveSeed(42, i) != s {
+			t.Errorf("Derived seed of run %d is not reproducible", i)
+		}
+	}
+	if DeriveSeed(42, 0) == DeriveSeed(43, 0) {
+		t.Errorf("Derived seeds do not depend on the seed")
+	}
+	// the random numbers of a derived seed are unrelated to the random numbers of the seed, e.g. of the simulation
+	sim, derived, other := rand.New(rand.NewSource(42)), rand.New(rand.NewSource(DeriveSeed(42, 1))), rand.New(rand.NewSource(DeriveSeed(42, 2)))
+	same := 0
+	for i := 0; i < 100; i++ {
+		s, d, o := sim.Int63(), derived.Int63(), other.Int63()
+		if s == d || d == o {
+			same++
+		}
+	}
+	if same > 0 {
+		t.Errorf("The random numbers of derived seeds must differ from the random numbers of the seed; %d identical of 100", same)
+	}
+}